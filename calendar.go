@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+// CalendarConfig describes an iCalendar file used to set the status
+type CalendarConfig struct {
+	Path string `json:"path"`
+
+	// Emoji is used for events that don't match a rule. It defaults to
+	// :calendar:.
+	Emoji string `json:"emoji,omitempty"`
+
+	// PrivateText replaces the title of private events. It defaults to
+	// "Busy".
+	PrivateText string `json:"private_text,omitempty"`
+
+	// HideTitles treats every event in the calendar as private
+	HideTitles bool `json:"hide_titles,omitempty"`
+
+	Rules []CalendarRule `json:"rules,omitempty"`
+}
+
+// CalendarRule maps events whose titles match a pattern to a status
+type CalendarRule struct {
+	Match string `json:"match"`
+	Emoji string `json:"emoji,omitempty"`
+	Text  string `json:"text,omitempty"`
+}
+
+// calendarStatus is the status for a calendar event occurrence
+type calendarStatus struct {
+	Key   string
+	Text  string
+	Emoji string
+	Start time.Time
+	End   time.Time
+}
+
+// Status returns the status text and emoji for an event
+func (c *CalendarConfig) Status(event *calendarEvent) (text, emoji string) {
+	emoji = c.Emoji
+	if emoji == "" {
+		emoji = ":calendar:"
+	}
+
+	if c.HideTitles || event.Class == "PRIVATE" || event.Class == "CONFIDENTIAL" {
+		text = c.PrivateText
+		if text == "" {
+			text = "Busy"
+		}
+		return
+	}
+
+	text = event.Summary
+	for _, rule := range c.Rules {
+		if matchesRule(rule.Match, event.Summary) {
+			if rule.Emoji != "" {
+				emoji = rule.Emoji
+			}
+			if rule.Text != "" {
+				text = rule.Text
+			}
+			break
+		}
+	}
+
+	return
+}
+
+// matchesRule returns true if text matches a rule pattern. Patterns are
+// case-insensitive regular expressions, or plain substrings if they aren't
+// valid expressions.
+func matchesRule(pattern, text string) bool {
+	if re, err := regexp.Compile("(?i)" + pattern); err == nil {
+		return re.MatchString(text)
+	}
+	return strings.Contains(strings.ToLower(text), strings.ToLower(pattern))
+}
+
+// calendarStatuses returns statuses for the timed events in all configured
+// calendars that overlap the range [from, to), ordered by start time
+func calendarStatuses(from, to time.Time) (statuses []calendarStatus) {
+	for i := range config.Calendars {
+		cal := &config.Calendars[i]
+
+		events, err := loadCalendar(cal.Path)
+		if err != nil {
+			dlog.Printf("Error loading calendar %s: %v", cal.Path, err)
+			continue
+		}
+
+		for _, o := range eventOccurrences(events, from, to) {
+			// All-day, free and cancelled events don't affect the status
+			if o.Event.AllDay || o.Event.Transparent || o.Event.Status == "CANCELLED" {
+				continue
+			}
+
+			text, emoji := cal.Status(o.Event)
+			statuses = append(statuses, calendarStatus{
+				Key:   fmt.Sprintf("%s@%d", o.Event.UID, o.Start.Unix()),
+				Text:  text,
+				Emoji: emoji,
+				Start: o.Start,
+				End:   o.End,
+			})
+		}
+	}
+
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[i].Start.Before(statuses[j].Start)
+	})
+
+	return
+}
+
+// currentCalendarStatus returns the status for the event in progress at now.
+// If several events overlap, the one that started most recently wins.
+func currentCalendarStatus(now time.Time) (status calendarStatus, found bool) {
+	statuses := calendarStatuses(now, now.Add(time.Second))
+	if len(statuses) == 0 {
+		return
+	}
+	return statuses[len(statuses)-1], true
+}
+
+// nextCalendarStatus returns the status for the event in progress at now, or
+// for the next event in the coming day
+func nextCalendarStatus(now time.Time) (status calendarStatus, found bool) {
+	if status, found = currentCalendarStatus(now); found {
+		return
+	}
+
+	statuses := calendarStatuses(now, now.AddDate(0, 0, 1))
+	if len(statuses) == 0 {
+		return
+	}
+	return statuses[0], true
+}
+
+// syncCalendars sets the status from the current calendar event. Each event
+// occurrence is applied once, so a status changed by hand during an event
+// isn't overwritten.
+func syncCalendars(now time.Time) (err error) {
+	status, found := currentCalendarStatus(now)
+	if !found || status.Key == cache.CalendarEvent {
+		return
+	}
+
+	dlog.Printf("Setting status from calendar event %s", status.Key)
+
+	s := OpenSession(config.APIToken)
+	if err = s.SetStatus(status.Text, status.Emoji, status.End); err != nil {
+		return
+	}
+
+	if i := indexOfUserByID(cache.Auth.UserID); i != -1 {
+		cache.Users[i].Profile.StatusText = status.Text
+		cache.Users[i].Profile.StatusEmoji = status.Emoji
+		cache.Users[i].Profile.StatusExpiration = status.End.Unix()
+	}
+
+	cache.CalendarEvent = status.Key
+	return alfred.SaveJSON(cacheFile, &cache)
+}
+
+// calendarItem returns an item describing the status set by the current or
+// next calendar event. Actioning it sets that status now.
+func calendarItem(now time.Time) (item alfred.Item, found bool) {
+	var status calendarStatus
+	if status, found = nextCalendarStatus(now); !found {
+		return
+	}
+
+	var when string
+	if status.Start.After(now) {
+		when = fmt.Sprintf("Next event %s–%s", formatExpiration(status.Start, now), status.End.Format("15:04"))
+	} else {
+		when = fmt.Sprintf("Current event until %s", status.End.Format("15:04"))
+	}
+
+	text := status.Text
+	emoji := status.Emoji
+	expires := status.End.Unix()
+
+	item = alfred.Item{
+		Title:    fmt.Sprintf("Calendar: %s", text),
+		Subtitle: when + ", action to set this status now",
+		Arg: &alfred.ItemArg{
+			Keyword: "status",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(statusConfig{StatusText: &text, StatusEmoji: &emoji, Expiration: &expires}),
+		},
+	}
+
+	if emojiFile, err := getEmojiIcon(emoji); err == nil {
+		item.Icon = emojiFile
+	}
+
+	return
+}
+
+func loadCalendar(filename string) (events []calendarEvent, err error) {
+	if strings.HasPrefix(filename, "~/") {
+		filename = path.Join(os.Getenv("HOME"), filename[2:])
+	}
+
+	var file *os.File
+	if file, err = os.Open(filename); err != nil {
+		return
+	}
+	defer file.Close()
+
+	return parseICS(file)
+}
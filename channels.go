@@ -3,8 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
+	"os"
+	"os/exec"
 	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/jason0x43/go-alfred"
 	"github.com/pkg/browser"
@@ -51,47 +56,21 @@ func (c ChannelsCommand) Items(arg, data string) (items []alfred.Item, err error
 
 		if property != "" {
 			if property == "pins" {
-				var pins []Pin
-				s := OpenSession(config.APIToken)
-				if pins, err = s.GetPins(cid); err == nil {
-					urlMatcher := regexp.MustCompile(`<?(https?://\S+)>?`)
-
-					for _, pin := range pins {
-						title := pin.Title()
-
-						item := alfred.Item{
-							Title: pin.Title(),
-						}
-
-						if urlMatcher.MatchString(title) {
-							url := urlMatcher.FindStringSubmatch(title)[1]
-
-							item.Arg = &alfred.ItemArg{
-								Keyword: "channels",
-								Mode:    alfred.ModeDo,
-								Data: alfred.Stringify(&channelConfig{
-									ToBrowse: &url,
-								}),
-							}
-						} else if pin.File != nil {
-							if icon, err := getFile(pin.File.Thumb64, ""); err == nil {
-								item.Icon = icon
-							}
-
-							item.Arg = &alfred.ItemArg{
-								Keyword: "channels",
-								Mode:    alfred.ModeDo,
-								Data: alfred.Stringify(&channelConfig{
-									ToBrowse: &pin.File.PrivateURL,
-								}),
-							}
-						}
-
-						items = append(items, item)
-					}
-
-					alfred.FuzzySort(items, arg)
+				items, err = pinItems(cid, arg)
+			} else if property == "bookmarks" {
+				items, err = bookmarkItems(cid, arg)
+			} else if property == "history" {
+				var latest string
+				if cfg.Latest != nil {
+					latest = *cfg.Latest
 				}
+				items, err = channelHistoryItems(cid, latest, arg)
+			} else if property == "thread" && cfg.ThreadTs != nil {
+				items, err = threadItems(cid, *cfg.ThreadTs, arg)
+			} else if property == "links" {
+				items = linkItems(cfg.Links, arg)
+			} else if property == "topic" || property == "purpose" {
+				items = channelTextItems(cid, property, arg)
 			}
 		} else {
 			if alfred.FuzzyMatches("open", arg) {
@@ -114,6 +93,24 @@ func (c ChannelsCommand) Items(arg, data string) (items []alfred.Item, err error
 				items = append(items, item)
 			}
 
+			if alfred.FuzzyMatches("history", arg) {
+				property := "history"
+				item := alfred.Item{
+					UID:          fmt.Sprintf("%s.channels.history", workflow.BundleID()),
+					Title:        "History",
+					Subtitle:     "List recent messages in this channel",
+					Autocomplete: "History",
+					Arg: &alfred.ItemArg{
+						Keyword: "channels",
+						Data: alfred.Stringify(&channelConfig{
+							Channel:  &cid,
+							Property: &property,
+						}),
+					},
+				}
+				items = append(items, item)
+			}
+
 			if alfred.FuzzyMatches("pins", arg) {
 				property = "pins"
 				item := alfred.Item{
@@ -132,6 +129,24 @@ func (c ChannelsCommand) Items(arg, data string) (items []alfred.Item, err error
 				items = append(items, item)
 			}
 
+			if alfred.FuzzyMatches("bookmarks", arg) {
+				property := "bookmarks"
+				item := alfred.Item{
+					UID:          fmt.Sprintf("%s.channels.bookmarks", workflow.BundleID()),
+					Title:        "Bookmarks",
+					Subtitle:     "List the bookmarks for this channel",
+					Autocomplete: "Bookmarks",
+					Arg: &alfred.ItemArg{
+						Keyword: "channels",
+						Data: alfred.Stringify(&channelConfig{
+							Channel:  &cid,
+							Property: &property,
+						}),
+					},
+				}
+				items = append(items, item)
+			}
+
 			if alfred.FuzzyMatches("members", arg) {
 				item := alfred.Item{
 					UID:          fmt.Sprintf("%s.channels.members", workflow.BundleID()),
@@ -147,10 +162,84 @@ func (c ChannelsCommand) Items(arg, data string) (items []alfred.Item, err error
 				}
 				items = append(items, item)
 			}
+
+			if alfred.FuzzyMatches("send message", arg) {
+				item := alfred.Item{
+					UID:          fmt.Sprintf("%s.channels.send", workflow.BundleID()),
+					Title:        "Send message",
+					Subtitle:     "Post a message to this channel",
+					Autocomplete: "Send message",
+					Arg: &alfred.ItemArg{
+						Keyword: "send",
+						Data: alfred.Stringify(&sendConfig{
+							Channel: &cid,
+						}),
+					},
+				}
+				items = append(items, item)
+			}
+
+			if alfred.FuzzyMatches("set topic", arg) {
+				property := "topic"
+				item := alfred.Item{
+					UID:          fmt.Sprintf("%s.channels.topic", workflow.BundleID()),
+					Title:        "Set topic",
+					Subtitle:     "Change the topic of this channel",
+					Autocomplete: "Set topic",
+					Arg: &alfred.ItemArg{
+						Keyword: "channels",
+						Data: alfred.Stringify(&channelConfig{
+							Channel:  &cid,
+							Property: &property,
+						}),
+					},
+				}
+				items = append(items, item)
+			}
+
+			if alfred.FuzzyMatches("set purpose", arg) {
+				property := "purpose"
+				item := alfred.Item{
+					UID:          fmt.Sprintf("%s.channels.purpose", workflow.BundleID()),
+					Title:        "Set purpose",
+					Subtitle:     "Change the purpose of this channel",
+					Autocomplete: "Set purpose",
+					Arg: &alfred.ItemArg{
+						Keyword: "channels",
+						Data: alfred.Stringify(&channelConfig{
+							Channel:  &cid,
+							Property: &property,
+						}),
+					},
+				}
+				items = append(items, item)
+			}
+
+			items = append(items, channelDetailItems(cid, arg)...)
 		}
+	} else if strings.HasPrefix(arg, "+") {
+		// A leading "+" creates a channel
+		items = createItems(arg[1:])
 	} else {
+		// A leading "!" lists channels with unread mentions first
+		sortByUnread := strings.HasPrefix(arg, "!")
+		arg = strings.TrimPrefix(arg, "!")
+
+		var onlyFavorites bool
+		arg, onlyFavorites = parseFavoritesFilter(arg)
+
+		counts, countsProblem := getChannelCounts()
+
+		ids := map[string]string{}
+
 		for _, channel := range cache.Channels {
+			if onlyFavorites && !isFavorite(channel.ID) {
+				continue
+			}
+
 			if alfred.FuzzyMatches(channel.Name, arg) {
+				ids[channel.Name] = channel.ID
+
 				item := alfred.Item{
 					Title:        channel.Name,
 					Autocomplete: channel.Name,
@@ -160,6 +249,22 @@ func (c ChannelsCommand) Items(arg, data string) (items []alfred.Item, err error
 					},
 				}
 
+				if isFavorite(channel.ID) {
+					item.Title = fmt.Sprintf("%s %s", FavoriteMarker, item.Title)
+				}
+
+				if count, found := counts[channel.ID]; found && count.HasUnreads {
+					item.Subtitle = describeCounts(count)
+					item.AddMod(alfred.ModCtrl, alfred.ItemMod{
+						Subtitle: "Mark as read",
+						Arg: &alfred.ItemArg{
+							Keyword: "channels",
+							Mode:    alfred.ModeDo,
+							Data:    alfred.Stringify(&channelConfig{ToMarkRead: &channel.ID}),
+						},
+					})
+				}
+
 				if !isInChannel(cache.Auth.UserID, &channel) {
 					item.Icon = "icon_faded.png"
 
@@ -167,6 +272,24 @@ func (c ChannelsCommand) Items(arg, data string) (items []alfred.Item, err error
 					// its UID so that Alfred will leave it after the
 					// subscribed channels
 					item.UID = ""
+
+					item.AddMod(alfred.ModAlt, alfred.ItemMod{
+						Subtitle: "Join this channel",
+						Arg: &alfred.ItemArg{
+							Keyword: "channels",
+							Mode:    alfred.ModeDo,
+							Data:    alfred.Stringify(&channelConfig{ToJoin: &channel.ID}),
+						},
+					})
+				} else {
+					item.AddMod(alfred.ModAlt, alfred.ItemMod{
+						Subtitle: "Leave this channel",
+						Arg: &alfred.ItemArg{
+							Keyword: "channels",
+							Mode:    alfred.ModeDo,
+							Data:    alfred.Stringify(&channelConfig{ToLeave: &channel.ID}),
+						},
+					})
 				}
 
 				item.AddMod(alfred.ModCmd, alfred.ItemMod{
@@ -183,12 +306,44 @@ func (c ChannelsCommand) Items(arg, data string) (items []alfred.Item, err error
 					},
 				})
 
+				addFavoriteMod(&item, channel.ID)
+
 				items = append(items, item)
 			}
 		}
 
 		alfred.FuzzySort(items, arg)
-		sort.Stable(bySubscription(items))
+
+		// Frequently used channels come first, then subscribed ones
+		usage := loadUsage()
+		now := time.Now()
+		rankItems(items, func(item alfred.Item) float64 {
+			score := usage.Frecency(ids[item.Autocomplete], now)
+			if item.Icon != "icon_faded.png" {
+				score += groupWeight
+			}
+			return score
+		})
+
+		if sortByUnread {
+			sort.SliceStable(items, func(i, j int) bool {
+				return counts[ids[items[i].Autocomplete]].UnreadCount > counts[ids[items[j].Autocomplete]].UnreadCount
+			})
+		}
+
+		favorites := favoriteSet()
+		sort.SliceStable(items, func(i, j int) bool {
+			return favorites[ids[items[i].Autocomplete]] && !favorites[ids[items[j].Autocomplete]]
+		})
+
+		// Say when unread counts are missing or out of date rather than
+		// leaving them out quietly
+		if countsProblem != "" && (sortByUnread || arg == "") {
+			items = append([]alfred.Item{{
+				Title:    "Unread counts unavailable",
+				Subtitle: countsProblem,
+			}}, items...)
+		}
 	}
 
 	return
@@ -204,6 +359,9 @@ func (c ChannelsCommand) Do(data string) (out string, err error) {
 	}
 
 	if cfg.ToOpen != nil {
+		if err := recordUsage(cfg.ToOpen.Channel); err != nil {
+			dlog.Printf("Error recording usage: %v", err)
+		}
 		err = browser.OpenURL(fmt.Sprintf("slack://channel?id=%s&team=%s", cfg.ToOpen.Channel, cfg.ToOpen.Team))
 	}
 
@@ -211,31 +369,711 @@ func (c ChannelsCommand) Do(data string) (out string, err error) {
 		err = browser.OpenURL(*cfg.ToBrowse)
 	}
 
+	if cfg.ToCreate != nil {
+		var channel Channel
+		var problems []string
+		if channel, problems, err = createChannel(*cfg.ToCreate); err != nil || channel.ID == "" {
+			return
+		}
+		if err := recordUsage(channel.ID); err != nil {
+			dlog.Printf("Error recording usage: %v", err)
+		}
+
+		out = fmt.Sprintf("Created #%s", channel.Name)
+		if len(problems) > 0 {
+			out = fmt.Sprintf("%s, but: %s", out, strings.Join(problems, "; "))
+		}
+
+		if err := browser.OpenURL(fmt.Sprintf("slack://channel?id=%s&team=%s", channel.ID, cache.Auth.TeamID)); err != nil {
+			dlog.Printf("Error opening channel: %v", err)
+		}
+	}
+
+	for _, link := range cfg.ToBrowseLinks {
+		if err = browser.OpenURL(link); err != nil {
+			return
+		}
+	}
+
+	if cfg.ToMarkRead != nil {
+		if err = markRead(*cfg.ToMarkRead); err != nil {
+			return
+		}
+		out = "Marked as read"
+	}
+
+	if cfg.ToBookmark != nil {
+		s := OpenSession(config.APIToken)
+		if err = s.AddBookmark(cfg.ToBookmark.Channel, cfg.ToBookmark.Title, cfg.ToBookmark.Link); err != nil {
+			return
+		}
+		out = fmt.Sprintf("Bookmarked %s", cfg.ToBookmark.Title)
+	}
+
+	if cfg.ToUnpin != nil {
+		s := OpenSession(config.APIToken)
+		if err = s.RemovePin(cfg.ToUnpin.Channel, cfg.ToUnpin.Ts, cfg.ToUnpin.File); err != nil {
+			return
+		}
+		out = "Unpinned"
+	}
+
+	if cfg.ToOpenMessage != nil {
+		var permalink string
+		s := OpenSession(config.APIToken)
+		if permalink, err = s.GetPermalink(cfg.ToOpenMessage.Channel, cfg.ToOpenMessage.Ts); err != nil {
+			return
+		}
+		err = browser.OpenURL(permalink)
+	}
+
+	if cfg.ToCopy != nil {
+		if err = copyToClipboard(*cfg.ToCopy); err != nil {
+			return
+		}
+		out = "Copied to clipboard"
+	}
+
+	if cfg.ToSetText != nil {
+		s := OpenSession(config.APIToken)
+		text := cfg.ToSetText.Text
+		i := indexOfChannelByID(cfg.ToSetText.Channel)
+
+		switch cfg.ToSetText.Property {
+		case "topic":
+			if err = s.SetChannelTopic(cfg.ToSetText.Channel, text); err != nil {
+				return
+			}
+			if i != -1 {
+				cache.Channels[i].Topic.Value = text
+			}
+			out = "Topic updated"
+		case "purpose":
+			if err = s.SetChannelPurpose(cfg.ToSetText.Channel, text); err != nil {
+				return
+			}
+			if i != -1 {
+				cache.Channels[i].Purpose.Value = text
+			}
+			out = "Purpose updated"
+		default:
+			return "", fmt.Errorf(`Unknown channel property "%s"`, cfg.ToSetText.Property)
+		}
+
+		delete(cache.ChannelInfo, cfg.ToSetText.Channel)
+		err = alfred.SaveJSON(cacheFile, &cache)
+	}
+
+	if cfg.ToJoin != nil {
+		s := OpenSession(config.APIToken)
+		if err = s.JoinChannel(*cfg.ToJoin); err != nil {
+			return
+		}
+		out, err = updateMembership(*cfg.ToJoin, true)
+	}
+
+	if cfg.ToLeave != nil {
+		s := OpenSession(config.APIToken)
+		if err = s.LeaveChannel(*cfg.ToLeave); err != nil {
+			return
+		}
+		out, err = updateMembership(*cfg.ToLeave, false)
+	}
+
 	return
 }
 
-type channelID struct {
-	Channel string
-	Team    string
+// getChannelCounts returns the cached unread counts for the user's
+// channels, keyed by channel ID, and a description of any counts that
+// couldn't be fetched. Counts more than a minute old are refreshed in the
+// background so the channel list never waits for them.
+func getChannelCounts() (counts map[string]ChannelCounts, problem string) {
+	now := time.Now()
+	if now.Sub(cache.CountsTime).Minutes() > 1.0 && now.Sub(cache.CountsRefreshTime).Minutes() > 1.0 {
+		startCountsRefresh()
+	}
+	return cache.Counts, cache.CountsProblem
 }
 
-type channelConfig struct {
-	Channel  *string
-	ToOpen   *channelID
-	Property *string
-	ToBrowse *string
+// startCountsRefresh runs refreshCounts in a separate process
+func startCountsRefresh() {
+	cache.CountsRefreshTime = time.Now()
+	if err := alfred.SaveJSON(cacheFile, &cache); err != nil {
+		dlog.Println("Unable to save cache:", err)
+		return
+	}
+
+	exe, _ := os.Executable()
+	cmd := exec.Command(exe, "-refresh-counts")
+	cmd.Dir = workflow.WorkflowDir()
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		dlog.Println("Unable to refresh unread counts:", err)
+	}
 }
 
-type bySubscription alfred.Items
+// refreshCounts fetches the unread counts of the user's channels. Only a few
+// requests are made at once to stay within Slack's rate limits. Channels
+// whose counts can't be fetched keep their last known counts.
+func refreshCounts() (err error) {
+	s := OpenSession(config.APIToken)
+	uid := cache.Auth.UserID
+
+	var member []string
+	for i := range cache.Channels {
+		if isInChannel(uid, &cache.Channels[i]) {
+			member = append(member, cache.Channels[i].ID)
+		}
+	}
+
+	type result struct {
+		channel string
+		count   ChannelCounts
+		err     error
+	}
+
+	jobs := make(chan string)
+	results := make(chan result)
+
+	for w := 0; w < maxCountRequests; w++ {
+		go func() {
+			for cid := range jobs {
+				count, err := s.GetUnreadCount(cid)
+				results <- result{channel: cid, count: count, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for _, cid := range member {
+			jobs <- cid
+		}
+		close(jobs)
+	}()
+
+	counts := map[string]ChannelCounts{}
+	unsupported := false
+	failed := 0
+	var lastErr error
 
-func (b bySubscription) Len() int {
-	return len(b)
+	for range member {
+		r := <-results
+		switch {
+		case r.err == errCountsUnsupported:
+			unsupported = true
+		case r.err != nil:
+			failed++
+			lastErr = r.err
+			if count, found := cache.Counts[r.channel]; found {
+				counts[r.channel] = count
+			}
+		default:
+			counts[r.channel] = r.count
+		}
+	}
+
+	problem := ""
+	if unsupported {
+		problem = errCountsUnsupported.Error()
+	} else if failed > 0 {
+		problem = fmt.Sprintf("Couldn't update %d %s: %v", failed, plural(failed, "channel", "channels"), lastErr)
+	}
+
+	// The cache may have changed while the counts were being fetched
+	if err = alfred.LoadJSON(cacheFile, &cache); err != nil {
+		return
+	}
+	cache.Counts = counts
+	cache.CountsProblem = problem
+	cache.CountsTime = time.Now()
+	return alfred.SaveJSON(cacheFile, &cache)
 }
 
-func (b bySubscription) Swap(i, j int) {
-	b[i], b[j] = b[j], b[i]
+// describeCounts returns a short description of a channel's unread counts,
+// like "12 unread"
+func describeCounts(count ChannelCounts) string {
+	if count.UnreadCount > 0 {
+		return fmt.Sprintf("%d unread", count.UnreadCount)
+	}
+	return "Unread messages"
 }
 
-func (b bySubscription) Less(i, j int) bool {
-	return b[i].Icon != "icon_faded.png" && b[j].Icon == "icon_faded.png"
+// markRead marks everything in a channel as read
+func markRead(cid string) (err error) {
+	s := OpenSession(config.APIToken)
+
+	var messages []Message
+	if messages, _, err = s.GetHistory(cid, "", 1); err != nil {
+		return
+	}
+	if len(messages) == 0 {
+		return
+	}
+
+	if err = s.MarkChannel(cid, messages[0].Ts); err != nil {
+		return
+	}
+
+	if count, found := cache.Counts[cid]; found {
+		count.HasUnreads = false
+		count.UnreadCount = 0
+		count.LastRead = messages[0].Ts
+		cache.Counts[cid] = count
+		err = alfred.SaveJSON(cacheFile, &cache)
+	}
+
+	return
+}
+
+// updateMembership adds or removes the current user from a cached channel's
+// member list so that the channel list reflects a join or leave immediately
+func updateMembership(cid string, joined bool) (out string, err error) {
+	var channel Channel
+	if channel, err = setMember(cid, cache.Auth.UserID, joined); err != nil || channel.ID == "" {
+		return
+	}
+
+	if joined {
+		out = fmt.Sprintf("Joined #%s", channel.Name)
+	} else {
+		out = fmt.Sprintf("Left #%s", channel.Name)
+	}
+	return
+}
+
+// setMember adds or removes a user from a cached channel's member list and
+// returns the updated channel
+func setMember(cid, uid string, member bool) (channel Channel, err error) {
+	i := indexOfChannelByID(cid)
+	if i == -1 {
+		return
+	}
+
+	var members []string
+	for _, m := range cache.Channels[i].Members {
+		if m != uid {
+			members = append(members, m)
+		}
+	}
+	if member {
+		members = append(members, uid)
+	}
+	cache.Channels[i].Members = members
+	delete(cache.ChannelInfo, cid)
+
+	err = alfred.SaveJSON(cacheFile, &cache)
+	return cache.Channels[i], err
+}
+
+// getChannelInfo returns up-to-date information about a channel. Channel
+// info is cached for a minute so that typing in a channel's menu doesn't make
+// a request for every keystroke.
+func getChannelInfo(cid string) (channel Channel, err error) {
+	if info, found := cache.ChannelInfo[cid]; found && time.Now().Sub(info.Time).Minutes() <= 1.0 {
+		return info.Channel, nil
+	}
+
+	s := OpenSession(config.APIToken)
+	if channel, err = s.GetChannelInfo(cid); err != nil {
+		return
+	}
+
+	if cache.ChannelInfo == nil {
+		cache.ChannelInfo = map[string]ChannelInfo{}
+	}
+	cache.ChannelInfo[cid] = ChannelInfo{Channel: channel, Time: time.Now()}
+	err = alfred.SaveJSON(cacheFile, &cache)
+	return
+}
+
+// channelDetailItems returns informational items about a channel. Their
+// values can be copied or shown in large type.
+func channelDetailItems(cid, arg string) (items []alfred.Item) {
+	channel, found := getChannel(cid)
+
+	if info, err := getChannelInfo(cid); err == nil {
+		channel = info
+	} else if !found {
+		dlog.Printf("Unable to get channel info: %v", err)
+		return
+	}
+
+	creator := channel.Creator
+	if user, found := getUser(channel.Creator); found {
+		creator = user.Name
+	}
+
+	privacy := "Public"
+	if channel.IsPrivate {
+		privacy = "Private"
+	}
+
+	sharing := "Not shared"
+	if channel.IsExtShared {
+		sharing = "Shared with external organizations"
+	} else if channel.IsShared {
+		sharing = "Shared with other workspaces"
+	}
+
+	archived := "No"
+	if channel.IsArchived {
+		archived = "Yes"
+	}
+
+	topic, _ := renderMrkdwn(channel.Topic.Value)
+	purpose, _ := renderMrkdwn(channel.Purpose.Value)
+
+	numMembers := channel.NumMembers
+	if numMembers == 0 {
+		numMembers = len(channel.Members)
+	}
+
+	details := []struct {
+		label string
+		value string
+	}{
+		{"Topic", topic},
+		{"Purpose", purpose},
+		{"Creator", creator},
+		{"Created", time.Unix(channel.Created, 0).Format("Jan 2, 2006")},
+		{"Member count", strconv.Itoa(numMembers)},
+		{"Privacy", privacy},
+		{"Sharing", sharing},
+		{"Archived", archived},
+	}
+
+	for _, detail := range details {
+		if alfred.FuzzyMatches(detail.label+":", arg) {
+			items = append(items, alfred.Item{
+				Title: fmt.Sprintf("%s: %s", detail.label, detail.value),
+				Text: &alfred.ItemText{
+					Copy:      detail.value,
+					LargeType: detail.value,
+				},
+			})
+		}
+	}
+
+	return
+}
+
+// pinItems returns the items for a channel's pins. Actioning a pin opens it
+// in Slack.
+func pinItems(cid, arg string) (items []alfred.Item, err error) {
+	var pins []Pin
+	s := OpenSession(config.APIToken)
+	if pins, err = s.GetPins(cid); err != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, pin := range pins {
+		title, links := renderMrkdwn(pin.Title())
+
+		subtitle := formatAge(time.Unix(pin.Created, 0), now)
+		if user, found := getUser(pin.CreatedBy); found {
+			subtitle = fmt.Sprintf("Pinned by %s · %s", user.Name, subtitle)
+		}
+
+		item := alfred.Item{
+			Title:    title,
+			Subtitle: subtitle,
+			Text: &alfred.ItemText{
+				Copy:      title,
+				LargeType: title,
+			},
+		}
+
+		if permalink := pin.Permalink(); permalink != "" {
+			item.Arg = &alfred.ItemArg{
+				Keyword: "channels",
+				Mode:    alfred.ModeDo,
+				Data:    alfred.Stringify(&channelConfig{ToBrowse: &permalink}),
+			}
+		}
+
+		unpin := pinID{Channel: cid}
+		if pin.Message != nil {
+			unpin.Ts = pin.Message.Ts
+			addThreadMod(&item, cid, Message{
+				Ts:         pin.Message.Ts,
+				ThreadTs:   pin.Message.ThreadTs,
+				ReplyCount: pin.Message.ReplyCount,
+			})
+		} else if pin.File != nil {
+			unpin.File = pin.File.ID
+			if icon, found := getThumbnail(pin.File, "64", len(items) < maxThumbnailDownloads); found {
+				item.Icon = icon
+			}
+			addDownloadMod(&item, pin.File)
+		}
+
+		item.AddMod(alfred.ModAlt, alfred.ItemMod{
+			Subtitle: "Copy the text",
+			Arg: &alfred.ItemArg{
+				Keyword: "channels",
+				Mode:    alfred.ModeDo,
+				Data:    alfred.Stringify(&channelConfig{ToCopy: &title}),
+			},
+		})
+
+		// One link is opened directly; several are listed so each can be
+		// opened on its own
+		if len(links) == 1 {
+			item.AddMod(alfred.ModCtrl, alfred.ItemMod{
+				Subtitle: fmt.Sprintf("Open %s", links[0]),
+				Arg: &alfred.ItemArg{
+					Keyword: "channels",
+					Mode:    alfred.ModeDo,
+					Data:    alfred.Stringify(&channelConfig{ToBrowse: &links[0]}),
+				},
+			})
+		} else if len(links) > 1 {
+			property := "links"
+			item.AddMod(alfred.ModCtrl, alfred.ItemMod{
+				Subtitle: fmt.Sprintf("List its %d links", len(links)),
+				Arg: &alfred.ItemArg{
+					Keyword: "channels",
+					Data: alfred.Stringify(&channelConfig{
+						Channel:  &cid,
+						Property: &property,
+						Links:    links,
+					}),
+				},
+			})
+		}
+
+		item.AddMod(alfred.ModFn, alfred.ItemMod{
+			Subtitle: "Unpin",
+			Arg: &alfred.ItemArg{
+				Keyword: "channels",
+				Mode:    alfred.ModeDo,
+				Data:    alfred.Stringify(&channelConfig{ToUnpin: &unpin}),
+			},
+		})
+
+		items = append(items, item)
+	}
+
+	alfred.FuzzySort(items, arg)
+	return
+}
+
+// linkItems lists the links in a message. Each one can be opened on its own,
+// or they can all be opened at once.
+func linkItems(links []string, arg string) (items []alfred.Item) {
+	for i := range links {
+		if alfred.FuzzyMatches(links[i], arg) {
+			items = append(items, alfred.Item{
+				Title:    links[i],
+				Subtitle: "Open this link",
+				Arg: &alfred.ItemArg{
+					Keyword: "channels",
+					Mode:    alfred.ModeDo,
+					Data:    alfred.Stringify(&channelConfig{ToBrowse: &links[i]}),
+				},
+			})
+		}
+	}
+
+	if arg == "" {
+		items = append([]alfred.Item{{
+			Title:    fmt.Sprintf("Open all %d links", len(links)),
+			Subtitle: "Open every link in the message",
+			Arg: &alfred.ItemArg{
+				Keyword: "channels",
+				Mode:    alfred.ModeDo,
+				Data:    alfred.Stringify(&channelConfig{ToBrowseLinks: links}),
+			},
+		}}, items...)
+	}
+
+	return
+}
+
+// bookmarkItems returns the items for a channel's bookmarks. A URL in the
+// query, optionally followed by a title, can be added as a new bookmark.
+func bookmarkItems(cid, arg string) (items []alfred.Item, err error) {
+	fields := strings.Fields(arg)
+	if len(fields) > 0 && (strings.HasPrefix(fields[0], "https://") || strings.HasPrefix(fields[0], "http://")) {
+		link := fields[0]
+		title := strings.Join(fields[1:], " ")
+		if title == "" {
+			title = link
+		}
+
+		items = append(items, alfred.Item{
+			Title:    fmt.Sprintf("Add bookmark “%s”", title),
+			Subtitle: link,
+			Arg: &alfred.ItemArg{
+				Keyword: "channels",
+				Mode:    alfred.ModeDo,
+				Data: alfred.Stringify(&channelConfig{
+					ToBookmark: &bookmarkDraft{Channel: cid, Title: title, Link: link},
+				}),
+			},
+		})
+		return
+	}
+
+	var bookmarks []Bookmark
+	s := OpenSession(config.APIToken)
+	if bookmarks, err = s.GetBookmarks(cid); err != nil {
+		return
+	}
+
+	for _, bookmark := range bookmarks {
+		if !alfred.FuzzyMatches(bookmark.Title, arg) {
+			continue
+		}
+
+		link := bookmark.Link
+		item := alfred.Item{
+			Title:    bookmark.Title,
+			Subtitle: link,
+			Arg: &alfred.ItemArg{
+				Keyword: "channels",
+				Mode:    alfred.ModeDo,
+				Data:    alfred.Stringify(&channelConfig{ToBrowse: &link}),
+			},
+			Text: &alfred.ItemText{
+				Copy:      link,
+				LargeType: link,
+			},
+		}
+
+		if bookmark.Emoji != "" {
+			if emojiFile, err := getEmojiIcon(bookmark.Emoji); err == nil {
+				item.Icon = emojiFile
+			}
+		}
+
+		items = append(items, item)
+	}
+
+	alfred.FuzzySort(items, arg)
+
+	if len(items) == 0 {
+		items = append(items, alfred.Item{
+			Title:    "No bookmarks",
+			Subtitle: "Enter a URL and an optional title to add one",
+		})
+	}
+
+	return
+}
+
+// channelTextItems returns the items for editing a channel's topic or
+// purpose. The query is the new value.
+func channelTextItems(cid, property, arg string) (items []alfred.Item) {
+	channel, _ := getChannel(cid)
+	if info, err := getChannelInfo(cid); err == nil {
+		channel = info
+	}
+
+	current := channel.Topic.Value
+	if property == "purpose" {
+		current = channel.Purpose.Value
+	}
+
+	if arg == "" {
+		title := current
+		if title == "" {
+			title = fmt.Sprintf("No %s", property)
+		}
+
+		items = append(items, alfred.Item{
+			Title:        title,
+			Subtitle:     fmt.Sprintf("Type a new %s, or press Tab to edit the current one", property),
+			Autocomplete: current,
+		})
+
+		if current != "" {
+			items = append(items, alfred.Item{
+				Title:    fmt.Sprintf("Clear the %s", property),
+				Subtitle: fmt.Sprintf("Remove the current %s", property),
+				Arg: &alfred.ItemArg{
+					Keyword: "channels",
+					Mode:    alfred.ModeDo,
+					Data: alfred.Stringify(&channelConfig{
+						ToSetText: &channelText{Channel: cid, Property: property},
+					}),
+				},
+			})
+		}
+
+		return
+	}
+
+	subtitle := fmt.Sprintf("No current %s", property)
+	if current != "" {
+		subtitle = fmt.Sprintf("Currently “%s”", current)
+	}
+
+	items = append(items, alfred.Item{
+		Title:        fmt.Sprintf("Set %s to “%s”", property, arg),
+		Subtitle:     subtitle,
+		Autocomplete: current,
+		Arg: &alfred.ItemArg{
+			Keyword: "channels",
+			Mode:    alfred.ModeDo,
+			Data: alfred.Stringify(&channelConfig{
+				ToSetText: &channelText{Channel: cid, Property: property, Text: arg},
+			}),
+		},
+	})
+
+	return
+}
+
+type channelText struct {
+	Channel  string
+	Property string
+	Text     string
+}
+
+type bookmarkDraft struct {
+	Channel string
+	Title   string
+	Link    string
+}
+
+type pinID struct {
+	Channel string
+	Ts      string
+	File    string
+}
+
+type channelID struct {
+	Channel string
+	Team    string
+}
+
+// maxCountRequests is how many unread count requests are made at once
+const maxCountRequests = 3
+
+// ChannelInfo is a cached copy of a channel's details
+type ChannelInfo struct {
+	Channel Channel
+	Time    time.Time
+}
+
+type channelConfig struct {
+	Channel       *string
+	ToOpen        *channelID
+	Property      *string
+	ToBrowse      *string
+	ToJoin        *string
+	ToLeave       *string
+	ToSetText     *channelText
+	Latest        *string
+	ToOpenMessage *messageID
+	ToCopy        *string
+	ThreadTs      *string
+	ToBrowseLinks []string
+	ToUnpin       *pinID
+	ToBookmark    *bookmarkDraft
+	ToMarkRead    *string
+	ToCreate      *channelDraft
+	Links         []string
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+// maxChannelNameLength is the longest channel name Slack accepts
+const maxChannelNameLength = 80
+
+// channelNamePattern matches the characters Slack allows in channel names
+var channelNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// channelDraft describes a channel to be created
+type channelDraft struct {
+	Name    string
+	Private bool
+	Purpose string
+	Users   []string
+}
+
+// parseChannelDraft parses a query like "design-team Where we talk about
+// design @bob @alice" into a channel name, a purpose and users to invite.
+// Names after an @ that don't match a cached user are returned as unknown.
+func parseChannelDraft(query string) (draft channelDraft, unknown []string) {
+	words := strings.Fields(query)
+	if len(words) == 0 {
+		return
+	}
+
+	draft.Name = strings.TrimPrefix(words[0], "#")
+
+	var purpose []string
+	for _, word := range words[1:] {
+		if !strings.HasPrefix(word, "@") {
+			purpose = append(purpose, word)
+			continue
+		}
+
+		name := strings.TrimRight(word[1:], ",;")
+		if user, found := getUserByName(name); found {
+			draft.Users = append(draft.Users, user.ID)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	draft.Purpose = strings.Join(purpose, " ")
+
+	return
+}
+
+// validateChannelName checks a name against Slack's channel naming rules
+func validateChannelName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("Enter a channel name")
+	case len(name) > maxChannelNameLength:
+		return fmt.Errorf("Names must be %d characters or less", maxChannelNameLength)
+	case strings.ToLower(name) != name:
+		return fmt.Errorf("Names must be lowercase")
+	case !channelNamePattern.MatchString(name):
+		return fmt.Errorf("Names can only contain letters, numbers, hyphens and underscores")
+	}
+
+	if _, found := getChannelByName(name); found {
+		return fmt.Errorf("#%s already exists", name)
+	}
+
+	return nil
+}
+
+// createItems returns the item that creates a channel described by the query.
+// Alt creates a private channel instead of a public one.
+func createItems(query string) (items []alfred.Item) {
+	draft, unknown := parseChannelDraft(query)
+
+	if err := validateChannelName(draft.Name); err != nil {
+		return []alfred.Item{{
+			Title:    "Create channel",
+			Subtitle: err.Error(),
+		}}
+	}
+
+	if len(unknown) > 0 {
+		return []alfred.Item{{
+			Title:    fmt.Sprintf("Create #%s", draft.Name),
+			Subtitle: fmt.Sprintf("Unknown %s: @%s", plural(len(unknown), "user", "users"), strings.Join(unknown, ", @")),
+		}}
+	}
+
+	var details []string
+	if draft.Purpose != "" {
+		details = append(details, draft.Purpose)
+	}
+	if len(draft.Users) > 0 {
+		details = append(details, fmt.Sprintf("Invite %d %s", len(draft.Users), plural(len(draft.Users), "user", "users")))
+	}
+
+	item := alfred.Item{
+		Title:    fmt.Sprintf("Create #%s", draft.Name),
+		Subtitle: strings.Join(append([]string{"Public channel"}, details...), " · "),
+		Arg: &alfred.ItemArg{
+			Keyword: "channels",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(&channelConfig{ToCreate: &draft}),
+		},
+	}
+
+	private := draft
+	private.Private = true
+	item.AddMod(alfred.ModAlt, alfred.ItemMod{
+		Subtitle: strings.Join(append([]string{"Private channel"}, details...), " · "),
+		Arg: &alfred.ItemArg{
+			Keyword: "channels",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(&channelConfig{ToCreate: &private}),
+		},
+	})
+
+	return append(items, item)
+}
+
+// createChannel creates a channel, sets its purpose and invites users, then
+// adds it to the cache. Once the channel exists, failures to set it up are
+// returned as problems rather than errors so that it can still be opened, and
+// a failure to save the cache is only logged.
+func createChannel(draft channelDraft) (channel Channel, problems []string, err error) {
+	s := OpenSession(config.APIToken)
+
+	if channel, err = s.CreateChannel(draft.Name, draft.Private); err != nil {
+		return
+	}
+
+	channel.Members = []string{cache.Auth.UserID}
+
+	if draft.Purpose != "" {
+		if err := s.SetChannelPurpose(channel.ID, draft.Purpose); err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			channel.Purpose.Value = draft.Purpose
+		}
+	}
+
+	if len(draft.Users) > 0 {
+		if err := s.InviteToChannel(channel.ID, draft.Users); err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			channel.Members = append(channel.Members, draft.Users...)
+		}
+	}
+
+	cache.Channels = append(cache.Channels, channel)
+	if err := alfred.SaveJSON(cacheFile, &cache); err != nil {
+		dlog.Printf("Unable to save cache: %v", err)
+	}
+
+	return
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseChannelDraft tests splitting a query into a name, purpose and
+// invited users
+func TestParseChannelDraft(t *testing.T) {
+	oldUsers := cache.Users
+	defer func() { cache.Users = oldUsers }()
+
+	cache.Users = []User{{ID: "U1", Name: "bob"}, {ID: "U2", Name: "alice"}}
+
+	draft, unknown := parseChannelDraft("#design Where we talk @bob, @alice @carol")
+	if draft.Name != "design" {
+		t.Errorf("Unexpected name %q", draft.Name)
+	}
+	if draft.Purpose != "Where we talk" {
+		t.Errorf("Unexpected purpose %q", draft.Purpose)
+	}
+	if !reflect.DeepEqual(draft.Users, []string{"U1", "U2"}) {
+		t.Errorf("Unexpected users %v", draft.Users)
+	}
+	if !reflect.DeepEqual(unknown, []string{"carol"}) {
+		t.Errorf("Unexpected unknown users %v", unknown)
+	}
+}
+
+// TestValidateChannelName tests Slack's channel naming rules
+func TestValidateChannelName(t *testing.T) {
+	oldChannels := cache.Channels
+	defer func() { cache.Channels = oldChannels }()
+
+	cache.Channels = []Channel{{ID: "C1", Name: "general"}}
+
+	valid := []string{"design", "design-team", "team_2017"}
+	for _, name := range valid {
+		if err := validateChannelName(name); err != nil {
+			t.Errorf("Expected %q to be valid: %v", name, err)
+		}
+	}
+
+	invalid := []string{"", "Design", "design team", "design.team", "general", strings.Repeat("a", 81)}
+	for _, name := range invalid {
+		if err := validateChannelName(name); err == nil {
+			t.Errorf("Expected %q to be invalid", name)
+		}
+	}
+}
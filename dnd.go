@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+// DNDCommand shows and updates the Do Not Disturb state
+type DNDCommand struct{}
+
+// About returns information about a command
+func (c DNDCommand) About() alfred.CommandDef {
+	return alfred.CommandDef{
+		Keyword:     "dnd",
+		Description: "Snooze notifications",
+		IsEnabled:   config.APIToken != "",
+		Arg: &alfred.ItemArg{
+			Keyword: "dnd",
+		},
+	}
+}
+
+// Items returns a list of filter items
+func (c DNDCommand) Items(arg, data string) (items []alfred.Item, err error) {
+	var info DNDInfo
+	if info, err = getDNDInfo(); err != nil {
+		return
+	}
+
+	now := time.Now()
+	arg = strings.TrimSpace(arg)
+
+	if minutes, err := parseMinutes(arg, now); err == nil {
+		items = append(items, snoozeItem(minutes, now))
+	} else {
+		for _, minutes := range []int{20, 60, 120, 240, 480} {
+			item := snoozeItem(minutes, now)
+			if alfred.FuzzyMatches(item.Title, arg) {
+				items = append(items, item)
+			}
+		}
+		alfred.FuzzySort(items, arg)
+	}
+
+	item := alfred.Item{
+		UID: fmt.Sprintf("%s.dnd.state", workflow.BundleID()),
+	}
+
+	if info.IsActive(now) {
+		until := info.Until(now)
+		item.Title = fmt.Sprintf("%s Notifications paused until %s", DNDMarker, until.Format("15:04"))
+		item.Subtitle = fmt.Sprintf("%s remaining", formatRemaining(until.Sub(now)))
+
+		if info.Snoozed(now) {
+			item.Subtitle += ", action to end the snooze"
+			item.Arg = &alfred.ItemArg{
+				Keyword: "dnd",
+				Mode:    alfred.ModeDo,
+				Data:    alfred.Stringify(dndConfig{EndSnooze: true}),
+			}
+		} else {
+			item.Subtitle += ", action to end Do Not Disturb"
+			item.Arg = &alfred.ItemArg{
+				Keyword: "dnd",
+				Mode:    alfred.ModeDo,
+				Data:    alfred.Stringify(dndConfig{EndDND: true}),
+			}
+		}
+	} else {
+		item.Title = "Notifications are on"
+		if info.Enabled && info.NextStart != 0 {
+			item.Subtitle = "Do Not Disturb starts " + formatExpiration(time.Unix(info.NextStart, 0), now)
+		} else {
+			item.Subtitle = "Enter a number of minutes or a duration to snooze"
+		}
+	}
+
+	items = append([]alfred.Item{item}, items...)
+
+	return
+}
+
+// Do implements the command
+func (c DNDCommand) Do(data string) (out string, err error) {
+	var cfg dndConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			return "", fmt.Errorf("Error unmarshalling data: %v", err)
+		}
+	}
+
+	s := OpenSession(config.APIToken)
+
+	if cfg.Snooze > 0 {
+		var endTime int64
+		if endTime, err = s.SetSnooze(cfg.Snooze); err != nil {
+			return
+		}
+		cache.DND.SnoozeEnabled = true
+		cache.DND.SnoozeEndTime = endTime
+		out = fmt.Sprintf("Notifications snoozed until %s", time.Unix(endTime, 0).Format("15:04"))
+	}
+
+	if cfg.EndSnooze {
+		if err = s.EndSnooze(); err != nil {
+			return
+		}
+		cache.DND.SnoozeEnabled = false
+		out = "Snooze ended"
+	}
+
+	if cfg.EndDND {
+		if err = s.EndDND(); err != nil {
+			return
+		}
+		cache.DND.Enabled = false
+		out = "Do Not Disturb ended"
+	}
+
+	alfred.SaveJSON(cacheFile, &cache)
+	return
+}
+
+// getDNDInfo returns the cached Do Not Disturb state, refreshing it if it's
+// more than a minute old
+func getDNDInfo() (info DNDInfo, err error) {
+	if time.Now().Sub(cache.DNDTime).Minutes() > 1.0 {
+		s := OpenSession(config.APIToken)
+		if cache.DND, err = s.GetDNDInfo(); err != nil {
+			return
+		}
+		cache.DNDTime = time.Now()
+		if err = alfred.SaveJSON(cacheFile, &cache); err != nil {
+			return
+		}
+	}
+
+	return cache.DND, nil
+}
+
+// parseMinutes parses a number of minutes or a duration phrase like
+// "2 hours" or "until 17:30"
+func parseMinutes(arg string, now time.Time) (minutes int, err error) {
+	if minutes, err = strconv.Atoi(arg); err == nil {
+		if minutes <= 0 {
+			err = fmt.Errorf("The number of minutes must be positive")
+		}
+		return
+	}
+
+	var until time.Time
+	if until, err = parseExpiration(arg, now); err != nil {
+		return
+	}
+
+	return int(math.Ceil(until.Sub(now).Minutes())), nil
+}
+
+func snoozeItem(minutes int, now time.Time) alfred.Item {
+	duration := time.Duration(minutes) * time.Minute
+
+	return alfred.Item{
+		Title:    fmt.Sprintf("Snooze for %s", formatRemaining(duration)),
+		Subtitle: fmt.Sprintf("Pause notifications until %s", formatExpiration(now.Add(duration), now)),
+		Arg: &alfred.ItemArg{
+			Keyword: "dnd",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(dndConfig{Snooze: minutes}),
+		},
+	}
+}
+
+type dndConfig struct {
+	Snooze    int
+	EndSnooze bool
+	EndDND    bool
+}
@@ -85,6 +85,15 @@ func getEmojiFromSprite(name string) (filename string, err error) {
 	return
 }
 
+// getEmojiIcon returns an icon file for an emoji name, looking first in the
+// sprite sheet and then at the team's custom emoji
+func getEmojiIcon(name string) (filename string, err error) {
+	if filename, err = getEmojiFromSprite(name); err != nil {
+		filename, err = getEmojiFromSlack(name)
+	}
+	return
+}
+
 func getAllSpriteEmoji() (names []string, err error) {
 	if len(spriteInfo) == 0 {
 		if err = alfred.LoadJSON(path.Join(workflow.WorkflowDir(), "emoji.json"), &spriteInfo); err != nil {
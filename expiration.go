@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var expirationKeyword = regexp.MustCompile(`(?i)(?:^|\s)(?:for|until|till)\s`)
+var durationPart = regexp.MustCompile(`^(\d+(?:\.\d+)?|half an?|an?)\s*(weeks?|wks?|w|days?|d|hours?|hrs?|h|minutes?|mins?|m)\s*(?:,\s*|and\s+)?`)
+var clockTime = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?\s*(am|pm)?$`)
+
+// parseStatusExpiration splits a trailing duration phrase, like "for 45m" or
+// "until tomorrow", off of a status message. If the text doesn't end with a
+// duration phrase, ok will be false and message will be the original text.
+func parseStatusExpiration(text string, now time.Time) (message string, expiration time.Time, ok bool) {
+	matches := expirationKeyword.FindAllStringIndex(text, -1)
+
+	// Try the rightmost phrase first so that a message like "waiting for Bob
+	// for 10m" keeps its first "for"
+	for i := len(matches) - 1; i >= 0; i-- {
+		start := matches[i][0]
+		if t, err := parseExpiration(text[start:], now); err == nil {
+			return strings.TrimSpace(text[:start]), t, true
+		}
+	}
+
+	return text, time.Time{}, false
+}
+
+// parseExpiration returns the time described by a phrase like "for 45m",
+// "until 17:30", "until tomorrow", or a bare duration like "2 hours"
+func parseExpiration(phrase string, now time.Time) (expiration time.Time, err error) {
+	phrase = strings.ToLower(strings.TrimSpace(phrase))
+	fields := strings.SplitN(phrase, " ", 2)
+
+	switch fields[0] {
+	case "until", "till":
+		if len(fields) < 2 {
+			return expiration, fmt.Errorf(`Missing time in "%s"`, phrase)
+		}
+		return parseUntil(strings.TrimSpace(fields[1]), now)
+	case "for":
+		if len(fields) < 2 {
+			return expiration, fmt.Errorf(`Missing duration in "%s"`, phrase)
+		}
+		phrase = strings.TrimSpace(fields[1])
+	}
+
+	var duration time.Duration
+	if duration, err = parseDuration(phrase); err != nil {
+		return
+	}
+	return now.Add(duration), nil
+}
+
+// parseDuration parses durations like "45m", "1h30m", "2 hours", "an hour" or
+// "1 day and 2 hours"
+func parseDuration(text string) (duration time.Duration, err error) {
+	rest := strings.ToLower(strings.TrimSpace(text))
+	if rest == "" {
+		return 0, fmt.Errorf("Empty duration")
+	}
+
+	for rest != "" {
+		match := durationPart.FindStringSubmatch(rest)
+		if match == nil {
+			return 0, fmt.Errorf(`Invalid duration "%s"`, text)
+		}
+
+		var amount float64
+		switch match[1] {
+		case "a", "an":
+			amount = 1
+		case "half a", "half an":
+			amount = 0.5
+		default:
+			amount, _ = strconv.ParseFloat(match[1], 64)
+		}
+
+		var unit time.Duration
+		switch match[2][0] {
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		case 'h':
+			unit = time.Hour
+		default:
+			unit = time.Minute
+		}
+
+		duration += time.Duration(amount * float64(unit))
+		rest = rest[len(match[0]):]
+	}
+
+	if duration <= 0 {
+		return 0, fmt.Errorf(`Invalid duration "%s"`, text)
+	}
+
+	return
+}
+
+// parseUntil parses the target of an "until" phrase, like "17:30", "5pm",
+// "tomorrow", "tomorrow at 9am" or "monday"
+func parseUntil(text string, now time.Time) (t time.Time, err error) {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	day := midnight
+	explicitDay := false
+	fields := strings.SplitN(text, " ", 2)
+
+	switch fields[0] {
+	case "today", "eod":
+		return midnight.AddDate(0, 0, 1), nil
+	case "tomorrow":
+		day = midnight.AddDate(0, 0, 1)
+		explicitDay = true
+	default:
+		if weekday, found := parseWeekday(fields[0]); found {
+			offset := (int(weekday) - int(now.Weekday()) + 7) % 7
+			if offset == 0 {
+				offset = 7
+			}
+			day = midnight.AddDate(0, 0, offset)
+			explicitDay = true
+		}
+	}
+
+	if explicitDay {
+		if len(fields) == 1 {
+			return day, nil
+		}
+		text = strings.TrimPrefix(strings.TrimSpace(fields[1]), "at ")
+	} else if text == "end of day" {
+		return midnight.AddDate(0, 0, 1), nil
+	}
+
+	var hour, minute int
+	if hour, minute, err = parseClockTime(text); err != nil {
+		return
+	}
+
+	t = day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	if !explicitDay && !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return
+}
+
+// parseClockTime parses a time of day like "17:30", "5pm" or "noon"
+func parseClockTime(text string) (hour, minute int, err error) {
+	switch text {
+	case "noon":
+		return 12, 0, nil
+	case "midnight":
+		return 24, 0, nil
+	}
+
+	match := clockTime.FindStringSubmatch(text)
+	if match == nil {
+		return 0, 0, fmt.Errorf(`Invalid time "%s"`, text)
+	}
+
+	hour, _ = strconv.Atoi(match[1])
+	if match[2] != "" {
+		minute, _ = strconv.Atoi(match[2])
+	}
+
+	if hour > 23 || minute > 59 || (match[3] != "" && (hour < 1 || hour > 12)) {
+		return 0, 0, fmt.Errorf(`Invalid time "%s"`, text)
+	}
+
+	switch match[3] {
+	case "am":
+		hour %= 12
+	case "pm":
+		hour = hour%12 + 12
+	}
+
+	return
+}
+
+func parseWeekday(text string) (weekday time.Weekday, found bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if text == name || text == name[:3] {
+			return d, true
+		}
+	}
+	return
+}
+
+// formatExpiration describes when a status will expire relative to now
+func formatExpiration(t, now time.Time) string {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch {
+	case t.Before(midnight.AddDate(0, 0, 1)):
+		return "today at " + t.Format("15:04")
+	case t.Equal(midnight.AddDate(0, 0, 1)):
+		return "at the end of today"
+	case t.Before(midnight.AddDate(0, 0, 2)):
+		return "tomorrow at " + t.Format("15:04")
+	case t.Before(midnight.AddDate(0, 0, 7)):
+		return t.Format("Monday at 15:04")
+	}
+	return t.Format("Jan 2 at 15:04")
+}
+
+// formatRemaining returns a short description of a duration, like "1h 20m"
+func formatRemaining(d time.Duration) string {
+	if d < time.Minute {
+		return "less than a minute"
+	}
+
+	d = d.Round(time.Minute)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes > 0 && days == 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseStatusExpiration tests parseStatusExpiration
+func TestParseStatusExpiration(t *testing.T) {
+	// Wednesday afternoon
+	now := time.Date(2017, time.May, 10, 14, 20, 0, 0, time.Local)
+
+	tests := []struct {
+		text       string
+		message    string
+		expiration time.Time
+		ok         bool
+	}{
+		{"lunch for 45m", "lunch", now.Add(45 * time.Minute), true},
+		{"lunch for 45 minutes", "lunch", now.Add(45 * time.Minute), true},
+		{"meeting for 1h30m", "meeting", now.Add(90 * time.Minute), true},
+		{"meeting for an hour", "meeting", now.Add(time.Hour), true},
+		{"break for half an hour", "break", now.Add(30 * time.Minute), true},
+		{"away for 2 days and 3 hours", "away", now.Add(51 * time.Hour), true},
+		{"waiting for Bob for 10m", "waiting for Bob", now.Add(10 * time.Minute), true},
+		{"out until 17:30", "out", time.Date(2017, time.May, 10, 17, 30, 0, 0, time.Local), true},
+		{"out until 5pm", "out", time.Date(2017, time.May, 10, 17, 0, 0, 0, time.Local), true},
+		{"out until 9am", "out", time.Date(2017, time.May, 11, 9, 0, 0, 0, time.Local), true},
+		{"out until tomorrow", "out", time.Date(2017, time.May, 11, 0, 0, 0, 0, time.Local), true},
+		{"out until tomorrow at 10:15", "out", time.Date(2017, time.May, 11, 10, 15, 0, 0, time.Local), true},
+		{"vacation until monday", "vacation", time.Date(2017, time.May, 15, 0, 0, 0, 0, time.Local), true},
+		{"until noon", "", time.Date(2017, time.May, 11, 12, 0, 0, 0, time.Local), true},
+		{"waiting for Bob", "waiting for Bob", time.Time{}, false},
+		{"out until 25:00", "out until 25:00", time.Time{}, false},
+	}
+
+	for _, test := range tests {
+		message, expiration, ok := parseStatusExpiration(test.text, now)
+		if ok != test.ok || message != test.message || !expiration.Equal(test.expiration) {
+			t.Errorf("%q: got (%q, %v, %v), expected (%q, %v, %v)", test.text,
+				message, expiration, ok, test.message, test.expiration, test.ok)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+// FavoritesCommand lists favorite channels and users
+type FavoritesCommand struct{}
+
+// About returns information about a command
+func (c FavoritesCommand) About() alfred.CommandDef {
+	return alfred.CommandDef{
+		Keyword:     "favorites",
+		Description: "List favorite channels and users",
+		IsEnabled:   config.APIToken != "",
+		Arg: &alfred.ItemArg{
+			Keyword: "favorites",
+		},
+	}
+}
+
+// Items returns the items for the command
+func (c FavoritesCommand) Items(arg, data string) (items []alfred.Item, err error) {
+	if err = checkRefresh(); err != nil {
+		return
+	}
+
+	for _, id := range config.Favorites {
+		if channel, found := getChannel(id); found {
+			if alfred.FuzzyMatches(channel.Name, arg) {
+				items = append(items, alfred.Item{
+					Title:        fmt.Sprintf("%s #%s", FavoriteMarker, channel.Name),
+					Subtitle:     channel.Purpose.Value,
+					Autocomplete: channel.Name,
+					Arg: &alfred.ItemArg{
+						Keyword: "channels",
+						Data:    alfred.Stringify(&channelConfig{Channel: &channel.ID}),
+					},
+				})
+			}
+		} else if user, found := getUser(id); found {
+			if alfred.FuzzyMatches(user.Name, arg) || alfred.FuzzyMatches(user.Profile.RealName, arg) {
+				items = append(items, alfred.Item{
+					Title:        fmt.Sprintf("%s @%s", FavoriteMarker, user.Name),
+					Subtitle:     user.Profile.StatusText,
+					Autocomplete: user.Name,
+					Arg: &alfred.ItemArg{
+						Keyword: "users",
+						Data:    alfred.Stringify(&userConfig{User: user.ID}),
+					},
+				})
+			}
+		}
+	}
+
+	return
+}
+
+// favoriteCommand lists a favorite channel or user with the top-level
+// commands. Actioning it opens the channel or user.
+type favoriteCommand struct {
+	ID string
+}
+
+// About returns information about a command
+func (c favoriteCommand) About() alfred.CommandDef {
+	if channel, found := getChannel(c.ID); found {
+		return alfred.CommandDef{
+			Keyword:     "#" + channel.Name,
+			Description: fmt.Sprintf("%s Favorite channel", FavoriteMarker),
+			IsEnabled:   config.APIToken != "",
+			Arg: &alfred.ItemArg{
+				Keyword: "channels",
+				Data:    alfred.Stringify(&channelConfig{Channel: &channel.ID}),
+			},
+		}
+	}
+
+	if user, found := getUser(c.ID); found {
+		return alfred.CommandDef{
+			Keyword:     "@" + user.Name,
+			Description: fmt.Sprintf("%s Favorite user", FavoriteMarker),
+			IsEnabled:   config.APIToken != "",
+			Arg: &alfred.ItemArg{
+				Keyword: "users",
+				Data:    alfred.Stringify(&userConfig{User: user.ID}),
+			},
+		}
+	}
+
+	// The channel or user may be gone or not cached yet
+	return alfred.CommandDef{Keyword: c.ID}
+}
+
+// Do implements the command
+func (c FavoritesCommand) Do(data string) (out string, err error) {
+	var cfg favoritesConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			return "", fmt.Errorf("Error unmarshalling data: %v", err)
+		}
+	}
+
+	if cfg.ToToggle != nil {
+		if isFavorite(*cfg.ToToggle) {
+			removeFavorite(*cfg.ToToggle)
+			out = "Removed from favorites"
+		} else {
+			config.Favorites = append(config.Favorites, *cfg.ToToggle)
+			out = "Added to favorites"
+		}
+		err = alfred.SaveJSON(configFile, &config)
+	}
+
+	return
+}
+
+func isFavorite(id string) bool {
+	for _, fav := range config.Favorites {
+		if fav == id {
+			return true
+		}
+	}
+	return false
+}
+
+// favoriteSet returns the favorite IDs as a set, for lookups while sorting
+func favoriteSet() map[string]bool {
+	favorites := map[string]bool{}
+	for _, id := range config.Favorites {
+		favorites[id] = true
+	}
+	return favorites
+}
+
+func removeFavorite(id string) {
+	var favorites []string
+	for _, fav := range config.Favorites {
+		if fav != id {
+			favorites = append(favorites, fav)
+		}
+	}
+	config.Favorites = favorites
+}
+
+// addFavoriteMod lets a channel or user be starred or unstarred
+func addFavoriteMod(item *alfred.Item, id string) {
+	subtitle := "Add to favorites"
+	if isFavorite(id) {
+		subtitle = "Remove from favorites"
+	}
+
+	item.AddMod(alfred.ModFn, alfred.ItemMod{
+		Subtitle: subtitle,
+		Arg: &alfred.ItemArg{
+			Keyword: "favorites",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(&favoritesConfig{ToToggle: &id}),
+		},
+	})
+}
+
+// parseFavoritesFilter strips a leading "*" from a query, which limits a list
+// to favorites
+func parseFavoritesFilter(arg string) (query string, onlyFavorites bool) {
+	if strings.HasPrefix(arg, "*") {
+		return strings.TrimSpace(arg[1:]), true
+	}
+	return arg, false
+}
+
+type favoritesConfig struct {
+	ToToggle *string
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+// TestParseFavoritesFilter tests the "*" favorites prefix
+func TestParseFavoritesFilter(t *testing.T) {
+	tests := []struct {
+		arg           string
+		query         string
+		onlyFavorites bool
+	}{
+		{"", "", false},
+		{"general", "general", false},
+		{"*", "", true},
+		{"*gen", "gen", true},
+		{"* gen", "gen", true},
+		{"gen*", "gen*", false},
+	}
+
+	for _, test := range tests {
+		query, only := parseFavoritesFilter(test.arg)
+		if query != test.query || only != test.onlyFavorites {
+			t.Errorf("parseFavoritesFilter(%q) = %q, %v; want %q, %v", test.arg, query, only, test.query, test.onlyFavorites)
+		}
+	}
+}
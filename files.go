@@ -0,0 +1,403 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+	"github.com/pkg/browser"
+)
+
+// fileTypes are the file types that can be used with a type: filter
+var fileTypes = []struct {
+	name        string
+	description string
+}{
+	{"images", "Images"},
+	{"pdfs", "PDF files"},
+	{"snippets", "Code snippets"},
+	{"spaces", "Posts"},
+	{"gdocs", "Google Docs"},
+	{"zips", "Zip archives"},
+}
+
+// FilesCommand lists and searches files
+type FilesCommand struct{}
+
+// About returns information about a command
+func (c FilesCommand) About() alfred.CommandDef {
+	return alfred.CommandDef{
+		Keyword:     "files",
+		Description: "List and search files",
+		IsEnabled:   config.APIToken != "",
+		Arg: &alfred.ItemArg{
+			Keyword: "files",
+		},
+	}
+}
+
+// Items returns the items for the command
+func (c FilesCommand) Items(arg, data string) (items []alfred.Item, err error) {
+	if err = checkRefresh(); err != nil {
+		return
+	}
+
+	if completions, complete := fileTypeCompletions(arg); !complete {
+		return completions, nil
+	}
+	if completions, complete := searchCompletions(arg); !complete {
+		return completions, nil
+	}
+
+	var query fileQuery
+	if query, err = parseFileQuery(arg); err != nil {
+		items = append(items, alfred.Item{
+			Title:    "Invalid filter",
+			Subtitle: err.Error(),
+		})
+		return items, nil
+	}
+
+	s := OpenSession(config.APIToken)
+
+	var files []File
+	if query.Text != "" {
+		if files, err = s.SearchFiles(query.SearchQuery()); err != nil {
+			return
+		}
+	} else {
+		if files, err = s.ListFiles(query.Channel, query.User, query.Type); err != nil {
+			return
+		}
+	}
+
+	now := time.Now()
+	for _, file := range files {
+		if query.Type == "" || matchesFileType(file, query.Type) {
+			items = append(items, fileItem(file, now, len(items) < maxThumbnailDownloads))
+		}
+	}
+
+	if len(items) == 0 {
+		items = append(items, alfred.Item{
+			Title:    "No files found",
+			Subtitle: "Filter with in:#channel, from:@user or type:images",
+		})
+	}
+
+	return
+}
+
+// Do implements the command
+func (c FilesCommand) Do(data string) (out string, err error) {
+	var cfg filesConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			return "", fmt.Errorf("Error unmarshalling data: %v", err)
+		}
+	}
+
+	if cfg.ToBrowse != nil {
+		err = browser.OpenURL(*cfg.ToBrowse)
+	}
+
+	if cfg.ToOpen != nil {
+		err = browser.OpenURL(fmt.Sprintf("slack://file?team=%s&id=%s", cfg.ToOpen.Team, cfg.ToOpen.File))
+	}
+
+	if cfg.ToCopy != nil {
+		if err = copyToClipboard(*cfg.ToCopy); err != nil {
+			return
+		}
+		out = "Copied permalink to clipboard"
+	}
+
+	if cfg.ToDownload != nil {
+		var filename string
+		if filename, err = saveToDownloads(cfg.ToDownload.URL, cfg.ToDownload.Name, cfg.ToDownload.ID, cfg.ToDownload.MimeType); err != nil {
+			return
+		}
+		out = fmt.Sprintf("Downloaded %s", path.Base(filename))
+	}
+
+	return
+}
+
+// fileItem returns an item for a file. Actioning it opens the file in a
+// browser.
+func fileItem(file File, now time.Time, download bool) alfred.Item {
+	title := file.Title
+	if title == "" {
+		title = file.Name
+	}
+
+	subtitle := []string{file.PrettyType}
+	if user, found := getUser(file.User); found {
+		subtitle = append(subtitle, user.Name)
+	}
+	if len(file.Channels) > 0 {
+		if channel, found := getChannel(file.Channels[0]); found {
+			subtitle = append(subtitle, "#"+channel.Name)
+		}
+	}
+	subtitle = append(subtitle, formatAge(time.Unix(file.Created, 0), now), formatSize(file.Size))
+
+	item := alfred.Item{
+		Title:    title,
+		Subtitle: strings.Join(subtitle, " · "),
+		Arg: &alfred.ItemArg{
+			Keyword: "files",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(&filesConfig{ToBrowse: &file.Permalink}),
+		},
+	}
+
+	if icon, found := getThumbnail(&file, "64", download); found {
+		item.Icon = icon
+	}
+	if preview, found := getThumbnail(&file, "360", download); found {
+		item.Quicklook = preview
+	}
+
+	item.AddMod(alfred.ModCmd, alfred.ItemMod{
+		Subtitle: "Open in Slack",
+		Arg: &alfred.ItemArg{
+			Keyword: "files",
+			Mode:    alfred.ModeDo,
+			Data: alfred.Stringify(&filesConfig{
+				ToOpen: &fileID{File: file.ID, Team: cache.Auth.TeamID},
+			}),
+		},
+	})
+
+	item.AddMod(alfred.ModAlt, alfred.ItemMod{
+		Subtitle: "Copy permalink",
+		Arg: &alfred.ItemArg{
+			Keyword: "files",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(&filesConfig{ToCopy: &file.Permalink}),
+		},
+	})
+
+	addDownloadMod(&item, &file)
+
+	return item
+}
+
+// getThumbnail returns a thumbnail of a file. Thumbnails that haven't been
+// cached yet are only downloaded if download is true, so that long lists
+// don't wait on a request for every file.
+func getThumbnail(file *File, size string, download bool) (thumbnail string, found bool) {
+	if url, _ := fileVersionURL(file, size); url == "" {
+		return
+	}
+
+	if !download {
+		return getCachedFile(file, size)
+	}
+
+	thumbnail, err := getFile(file, size)
+	if err != nil {
+		dlog.Printf("Unable to get thumbnail: %v", err)
+		return "", false
+	}
+	return thumbnail, true
+}
+
+// addDownloadMod lets a file be downloaded to ~/Downloads
+func addDownloadMod(item *alfred.Item, file *File) {
+	if file.DownloadURL == "" {
+		return
+	}
+
+	item.AddMod(alfred.ModShift, alfred.ItemMod{
+		Subtitle: "Download to ~/Downloads",
+		Arg: &alfred.ItemArg{
+			Keyword: "files",
+			Mode:    alfred.ModeDo,
+			Data: alfred.Stringify(&filesConfig{
+				ToDownload: &fileDownload{URL: file.DownloadURL, Name: file.Name, ID: file.ID, MimeType: file.MimeType},
+			}),
+		},
+	})
+}
+
+// parseFileQuery parses a query like "in:#design from:@bob type:images logo"
+func parseFileQuery(arg string) (query fileQuery, err error) {
+	var words []string
+
+	for _, field := range strings.Fields(arg) {
+		switch {
+		case strings.HasPrefix(field, "in:"):
+			name := strings.TrimPrefix(field[3:], "#")
+			channel, found := getChannelByName(name)
+			if !found {
+				return query, fmt.Errorf("Unknown channel #%s", name)
+			}
+			query.Channel = channel.ID
+			query.ChannelName = channel.Name
+		case strings.HasPrefix(field, "from:"):
+			name := strings.TrimPrefix(field[5:], "@")
+			user, found := getUserByName(name)
+			if !found {
+				return query, fmt.Errorf("Unknown user @%s", name)
+			}
+			query.User = user.ID
+			query.UserName = user.Name
+		case strings.HasPrefix(field, "type:"):
+			query.Type = strings.ToLower(field[5:])
+			if !isFileType(query.Type) {
+				return query, fmt.Errorf(`Unknown file type "%s"`, query.Type)
+			}
+		default:
+			words = append(words, field)
+		}
+	}
+
+	query.Text = strings.Join(words, " ")
+	return
+}
+
+// SearchQuery returns the query for search.files
+func (q *fileQuery) SearchQuery() string {
+	parts := []string{q.Text}
+	if q.ChannelName != "" {
+		parts = append(parts, "in:#"+q.ChannelName)
+	}
+	if q.UserName != "" {
+		parts = append(parts, "from:@"+q.UserName)
+	}
+	return strings.Join(parts, " ")
+}
+
+// fileTypeCompletions returns completions for a type: filter at the end of a
+// query
+func fileTypeCompletions(query string) (items []alfred.Item, complete bool) {
+	prefix := ""
+	token := query
+	if i := strings.LastIndex(query, " "); i != -1 {
+		prefix = query[:i+1]
+		token = query[i+1:]
+	}
+
+	if !strings.HasPrefix(token, "type:") || isFileType(token[5:]) {
+		return nil, true
+	}
+
+	for _, t := range fileTypes {
+		if alfred.FuzzyMatches(t.name, token[5:]) {
+			items = append(items, alfred.Item{
+				Title:        "type:" + t.name,
+				Subtitle:     t.description,
+				Autocomplete: prefix + "type:" + t.name + " ",
+			})
+		}
+	}
+
+	return items, false
+}
+
+func isFileType(name string) bool {
+	for _, t := range fileTypes {
+		if t.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesFileType returns true if a file is of one of the types accepted by
+// files.list
+func matchesFileType(file File, fileType string) bool {
+	switch fileType {
+	case "images":
+		return strings.HasPrefix(file.MimeType, "image/")
+	case "pdfs":
+		return file.FileType == "pdf"
+	case "snippets":
+		return file.Mode == "snippet"
+	case "spaces":
+		return file.FileType == "space" || file.FileType == "post"
+	case "gdocs":
+		return strings.HasPrefix(file.FileType, "g")
+	case "zips":
+		return file.FileType == "zip"
+	}
+	return true
+}
+
+// formatSize returns a short description of a file size, like "1.2 MB"
+func formatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	value := float64(size)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f %s", value, units[i])
+}
+
+// downloadName returns a name to save a file under that can't point outside
+// the download directory. The file's ID is used if its name has nothing
+// usable left.
+func downloadName(name, id string) string {
+	name = path.Base(name)
+	switch name {
+	case "", ".", "..", "/":
+		return id
+	}
+	return name
+}
+
+// saveToDownloads downloads a file to ~/Downloads. Existing files
+// aren't overwritten; a number is added to the name instead.
+func saveToDownloads(url, name, id, mimeType string) (filename string, err error) {
+	dir := path.Join(os.Getenv("HOME"), "Downloads")
+	name = downloadName(name, id)
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	filename = path.Join(dir, name)
+	for i := 1; fileExists(filename); i++ {
+		filename = path.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+
+	err = downloadFile(url, filename, expectedContentType(mimeType))
+	return
+}
+
+type fileQuery struct {
+	Channel     string
+	ChannelName string
+	User        string
+	UserName    string
+	Type        string
+	Text        string
+}
+
+type fileID struct {
+	File string
+	Team string
+}
+
+type fileDownload struct {
+	URL      string
+	Name     string
+	ID       string
+	MimeType string
+}
+
+type filesConfig struct {
+	ToBrowse   *string
+	ToOpen     *fileID
+	ToCopy     *string
+	ToDownload *fileDownload
+}
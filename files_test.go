@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+// TestParseFileQuery tests parseFileQuery
+func TestParseFileQuery(t *testing.T) {
+	oldChannels, oldUsers := cache.Channels, cache.Users
+	defer func() { cache.Channels, cache.Users = oldChannels, oldUsers }()
+
+	cache.Channels = []Channel{{ID: "C1", Name: "design"}}
+	cache.Users = []User{{ID: "U1", Name: "bob"}}
+
+	query, err := parseFileQuery("in:#design logo from:@bob type:images v2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := fileQuery{
+		Channel:     "C1",
+		ChannelName: "design",
+		User:        "U1",
+		UserName:    "bob",
+		Type:        "images",
+		Text:        "logo v2",
+	}
+	if query != expected {
+		t.Errorf("Expected %+v, got %+v", expected, query)
+	}
+	if sq := query.SearchQuery(); sq != "logo v2 in:#design from:@bob" {
+		t.Errorf("Unexpected search query %q", sq)
+	}
+
+	for _, arg := range []string{"in:#nowhere", "from:@nobody", "type:movies"} {
+		if _, err := parseFileQuery(arg); err == nil {
+			t.Errorf("%q: expected an error", arg)
+		}
+	}
+}
+
+// TestFormatSize tests formatSize
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{
+		512:             "512 B",
+		2048:            "2.0 KB",
+		5 * 1024 * 1024: "5.0 MB",
+	}
+
+	for size, expected := range tests {
+		if formatted := formatSize(size); formatted != expected {
+			t.Errorf("formatSize(%d): expected %q, got %q", size, expected, formatted)
+		}
+	}
+}
+
+// TestDownloadName tests that downloads stay in the download directory
+func TestDownloadName(t *testing.T) {
+	tests := map[string]string{
+		"logo.png":                           "logo.png",
+		"../../Library/LaunchAgents/x.plist": "x.plist",
+		"..":                                 "F1",
+		"/":                                  "F1",
+		"":                                   "F1",
+		"designs/..":                         "F1",
+	}
+
+	for name, expected := range tests {
+		if actual := downloadName(name, "F1"); actual != expected {
+			t.Errorf("downloadName(%q) = %q, expected %q", name, actual, expected)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+// FocusBundle is the set of changes applied for the length of a focus session
+type FocusBundle struct {
+	Text     string   `json:"text"`
+	Emoji    string   `json:"emoji"`
+	Presence Presence `json:"presence,omitempty"`
+	Snooze   bool     `json:"snooze"`
+}
+
+var defaultFocusBundle = FocusBundle{
+	Text:     "Focusing",
+	Emoji:    ":headphones:",
+	Presence: PresenceAway,
+	Snooze:   true,
+}
+
+// focusSession is an active focus session. It records the state to restore
+// when the session ends.
+type focusSession struct {
+	End      int64
+	Bundle   FocusBundle
+	Text     string
+	Emoji    string
+	Expires  int64
+	Presence Presence
+}
+
+// FocusCommand starts and cancels focus sessions
+type FocusCommand struct{}
+
+// About returns information about a command
+func (c FocusCommand) About() alfred.CommandDef {
+	return alfred.CommandDef{
+		Keyword:     "focus",
+		Description: "Start a focus session",
+		IsEnabled:   config.APIToken != "",
+		Arg: &alfred.ItemArg{
+			Keyword: "focus",
+		},
+	}
+}
+
+// Items returns a list of filter items
+func (c FocusCommand) Items(arg, data string) (items []alfred.Item, err error) {
+	now := time.Now()
+	arg = strings.TrimSpace(arg)
+	bundle := getFocusBundle()
+
+	if minutes, err := parseMinutes(arg, now); err == nil {
+		items = append(items, focusItem(bundle, minutes, now))
+	} else {
+		for _, minutes := range []int{25, 50, 90, 120} {
+			item := focusItem(bundle, minutes, now)
+			if alfred.FuzzyMatches(item.Title, arg) {
+				items = append(items, item)
+			}
+		}
+		alfred.FuzzySort(items, arg)
+	}
+
+	if session, found := loadFocusSession(); found {
+		end := time.Unix(session.End, 0)
+		item := alfred.Item{
+			UID:      fmt.Sprintf("%s.focus.cancel", workflow.BundleID()),
+			Title:    fmt.Sprintf("Focusing until %s", end.Format("15:04")),
+			Subtitle: fmt.Sprintf("%s remaining, action to end the session now", formatRemaining(end.Sub(now))),
+			Arg: &alfred.ItemArg{
+				Keyword: "focus",
+				Mode:    alfred.ModeDo,
+				Data:    alfred.Stringify(focusConfig{Cancel: true}),
+			},
+		}
+		items = append([]alfred.Item{item}, items...)
+	}
+
+	return
+}
+
+// Do implements the command
+func (c FocusCommand) Do(data string) (out string, err error) {
+	var cfg focusConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			return "", fmt.Errorf("Error unmarshalling data: %v", err)
+		}
+	}
+
+	if cfg.Start > 0 {
+		var end time.Time
+		if end, err = startFocus(cfg.Start); err != nil {
+			return
+		}
+		out = fmt.Sprintf("Focusing until %s", end.Format("15:04"))
+	}
+
+	if cfg.Cancel {
+		if err = endFocus(true); err != nil {
+			return
+		}
+		out = "Focus session ended"
+	}
+
+	return
+}
+
+// startFocus applies the focus bundle for the given number of minutes and
+// starts a background process to restore the previous state afterwards
+func startFocus(minutes int) (end time.Time, err error) {
+	if err = checkRefresh(); err != nil {
+		return
+	}
+
+	i := indexOfUserByID(cache.Auth.UserID)
+	if i == -1 {
+		err = fmt.Errorf("The user cache is empty")
+		return
+	}
+
+	s := OpenSession(config.APIToken)
+	bundle := getFocusBundle()
+	end = time.Now().Add(time.Duration(minutes) * time.Minute)
+
+	// If a session is already running, keep the state from before it started
+	session, found := loadFocusSession()
+	if !found {
+		user := cache.Users[i]
+		session = focusSession{
+			Text:    user.Profile.StatusText,
+			Emoji:   user.Profile.StatusEmoji,
+			Expires: user.Profile.StatusExpiration,
+		}
+		if session.Presence, err = s.GetPresence(cache.Auth.UserID); err != nil {
+			return
+		}
+	}
+
+	session.End = end.Unix()
+	session.Bundle = bundle
+
+	if err = s.SetStatus(bundle.Text, bundle.Emoji, end); err != nil {
+		return
+	}
+	cache.Users[i].Profile.StatusText = bundle.Text
+	cache.Users[i].Profile.StatusEmoji = bundle.Emoji
+	cache.Users[i].Profile.StatusExpiration = end.Unix()
+
+	if bundle.Presence != "" {
+		if err = s.SetPresence(bundle.Presence); err != nil {
+			return
+		}
+		cache.Users[i].Presence = bundle.Presence
+	}
+
+	if bundle.Snooze {
+		var endTime int64
+		if endTime, err = s.SetSnooze(minutes); err != nil {
+			return
+		}
+		cache.DND.SnoozeEnabled = true
+		cache.DND.SnoozeEndTime = endTime
+	}
+
+	if err = alfred.SaveJSON(focusFile, &session); err != nil {
+		return
+	}
+	alfred.SaveJSON(cacheFile, &cache)
+
+	// Restore the previous state when the session ends, even if the workflow
+	// isn't run again before then
+	exe, _ := os.Executable()
+	cmd := exec.Command(exe, "-focus-end", strconv.FormatInt(session.End, 10))
+	cmd.Dir = workflow.WorkflowDir()
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		dlog.Println("Unable to start focus timer:", err)
+	}
+
+	return
+}
+
+// endFocus restores the state recorded when the active focus session started.
+// If the session is being cancelled early, the notification snooze is also
+// ended.
+func endFocus(cancelled bool) (err error) {
+	session, found := loadFocusSession()
+	if !found {
+		return fmt.Errorf("There is no active focus session")
+	}
+
+	s := OpenSession(config.APIToken)
+	now := time.Now()
+
+	// Don't restore a status that would have expired during the session
+	if session.Expires != 0 && !time.Unix(session.Expires, 0).After(now) {
+		session.Text, session.Emoji, session.Expires = "", "", 0
+	}
+
+	var expires time.Time
+	if session.Expires != 0 {
+		expires = time.Unix(session.Expires, 0)
+	}
+
+	if err = s.SetStatus(session.Text, session.Emoji, expires); err != nil {
+		return
+	}
+
+	i := indexOfUserByID(cache.Auth.UserID)
+	if i != -1 {
+		cache.Users[i].Profile.StatusText = session.Text
+		cache.Users[i].Profile.StatusEmoji = session.Emoji
+		cache.Users[i].Profile.StatusExpiration = session.Expires
+	}
+
+	if session.Bundle.Presence != "" && session.Presence != "" {
+		if err = s.SetPresence(session.Presence); err != nil {
+			return
+		}
+		if i != -1 {
+			cache.Users[i].Presence = session.Presence
+		}
+	}
+
+	if cancelled && session.Bundle.Snooze {
+		if err = s.EndSnooze(); err != nil {
+			dlog.Println("Unable to end snooze:", err)
+		}
+		cache.DND.SnoozeEnabled = false
+	}
+
+	alfred.SaveJSON(cacheFile, &cache)
+	return os.Remove(focusFile)
+}
+
+// waitForFocusEnd is run in a background process by startFocus. It waits
+// until the given end time and then ends the session, unless the session has
+// since been cancelled or replaced.
+func waitForFocusEnd(endArg string) {
+	end, err := strconv.ParseInt(endArg, 10, 64)
+	if err != nil {
+		dlog.Println("Invalid focus end time:", endArg)
+		return
+	}
+
+	time.Sleep(time.Unix(end, 0).Sub(time.Now()))
+
+	// The workflow has probably run since this process started
+	alfred.LoadJSON(configFile, &config)
+	alfred.LoadJSON(cacheFile, &cache)
+
+	if session, found := loadFocusSession(); found && session.End == end {
+		if err := endFocus(false); err != nil {
+			dlog.Println("Unable to end focus session:", err)
+		}
+	}
+}
+
+// checkFocus ends the active focus session if it should already have ended,
+// such as when the background process didn't survive a restart
+func checkFocus() {
+	if session, found := loadFocusSession(); found && time.Unix(session.End, 0).Before(time.Now()) {
+		if err := endFocus(false); err != nil {
+			dlog.Println("Unable to end focus session:", err)
+		}
+	}
+}
+
+func loadFocusSession() (session focusSession, found bool) {
+	if !fileExists(focusFile) {
+		return
+	}
+	if err := alfred.LoadJSON(focusFile, &session); err != nil {
+		dlog.Println("Error loading focus session:", err)
+		return
+	}
+	return session, session.End != 0
+}
+
+func getFocusBundle() FocusBundle {
+	if config.Focus != nil {
+		return *config.Focus
+	}
+	return defaultFocusBundle
+}
+
+func focusItem(bundle FocusBundle, minutes int, now time.Time) alfred.Item {
+	duration := time.Duration(minutes) * time.Minute
+
+	var changes []string
+	changes = append(changes, strings.TrimSpace(fmt.Sprintf("set status to “%s” %s", bundle.Text, bundle.Emoji)))
+	if bundle.Presence != "" {
+		changes = append(changes, fmt.Sprintf("set presence to %s", bundle.Presence))
+	}
+	if bundle.Snooze {
+		changes = append(changes, "snooze notifications")
+	}
+
+	item := alfred.Item{
+		Title: fmt.Sprintf("Focus for %s", formatRemaining(duration)),
+		Subtitle: fmt.Sprintf("Until %s: %s", now.Add(duration).Format("15:04"),
+			strings.Join(changes, ", ")),
+		Arg: &alfred.ItemArg{
+			Keyword: "focus",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(focusConfig{Start: minutes}),
+		},
+	}
+
+	if bundle.Emoji != "" {
+		if emojiFile, err := getEmojiIcon(bundle.Emoji); err == nil {
+			item.Icon = emojiFile
+		}
+	}
+
+	return item
+}
+
+type focusConfig struct {
+	Start  int
+	Cancel bool
+}
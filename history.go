@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+// maxStatusHistory is the number of statuses kept in the history
+const maxStatusHistory = 50
+
+// statusHistoryEntry is a status text and emoji combination that was set
+type statusHistoryEntry struct {
+	Text  string
+	Emoji string
+	Time  time.Time
+}
+
+func loadStatusHistory() (history []statusHistoryEntry) {
+	if !fileExists(historyFile) {
+		return
+	}
+	if err := alfred.LoadJSON(historyFile, &history); err != nil {
+		dlog.Println("Error loading status history:", err)
+	}
+	return
+}
+
+// recordStatus adds a status to the history. A status that's already in the
+// history is moved to the end rather than being added twice. If t is zero, a
+// status already in the history keeps its original time.
+func recordStatus(text, emoji string, t time.Time) (err error) {
+	if text == "" && emoji == "" {
+		return
+	}
+
+	history := addStatusHistory(loadStatusHistory(), statusHistoryEntry{
+		Text:  text,
+		Emoji: emoji,
+		Time:  t,
+	})
+
+	return alfred.SaveJSON(historyFile, &history)
+}
+
+func addStatusHistory(history []statusHistoryEntry, entry statusHistoryEntry) []statusHistoryEntry {
+	for i := range history {
+		if history[i].Text == entry.Text && history[i].Emoji == entry.Emoji {
+			if entry.Time.IsZero() {
+				entry.Time = history[i].Time
+			}
+			history = append(history[:i], history[i+1:]...)
+			break
+		}
+	}
+
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	history = append(history, entry)
+	if len(history) > maxStatusHistory {
+		history = history[len(history)-maxStatusHistory:]
+	}
+
+	return history
+}
+
+// previousStatus returns the most recent status in the history that isn't the
+// given current status
+func previousStatus(history []statusHistoryEntry, text, emoji string) (entry statusHistoryEntry, found bool) {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Text != text || history[i].Emoji != emoji {
+			return history[i], true
+		}
+	}
+	return
+}
+
+// historyItems returns items for past statuses matching the query, most
+// recent first
+func historyItems(history []statusHistoryEntry, arg string, now time.Time) (items []alfred.Item) {
+	for i := len(history) - 1; i >= 0; i-- {
+		entry := history[i]
+
+		if entry.Text == "" || entry.Text == arg || !alfred.FuzzyMatches(entry.Text, arg) {
+			continue
+		}
+
+		items = append(items, historyItem(entry, entry.Text,
+			fmt.Sprintf("Set %s ago", formatRemaining(now.Sub(entry.Time)))))
+	}
+
+	alfred.FuzzySort(items, arg)
+	return
+}
+
+func historyItem(entry statusHistoryEntry, title, subtitle string) alfred.Item {
+	text := entry.Text
+	emoji := entry.Emoji
+
+	item := alfred.Item{
+		Title:        strings.TrimSpace(title),
+		Subtitle:     subtitle,
+		Autocomplete: text,
+		Arg: &alfred.ItemArg{
+			Keyword: "status",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(statusConfig{StatusText: &text, StatusEmoji: &emoji}),
+		},
+	}
+
+	if emoji != "" {
+		if emojiFile, err := getEmojiIcon(emoji); err == nil {
+			item.Icon = emojiFile
+		}
+	}
+
+	return item
+}
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRecurrences limits how many occurrences of a recurring event are
+// examined, in case a rule has no end
+const maxRecurrences = 10000
+
+var icsDuration = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// calendarEvent is a VEVENT from an iCalendar file
+type calendarEvent struct {
+	UID          string
+	Summary      string
+	Class        string
+	Status       string
+	Transparent  bool
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	Rule         *recurrenceRule
+	ExDates      []time.Time
+	RecurrenceID time.Time
+}
+
+// recurrenceRule is the subset of an RRULE supported by the workflow
+type recurrenceRule struct {
+	Freq     string
+	Interval int
+	Count    int
+	Until    time.Time
+	ByDay    []time.Weekday
+}
+
+// eventOccurrence is a single occurrence of a possibly recurring event
+type eventOccurrence struct {
+	Event *calendarEvent
+	Start time.Time
+	End   time.Time
+}
+
+// parseICS reads the events from an iCalendar stream
+func parseICS(r io.Reader) (events []calendarEvent, err error) {
+	var lines []string
+
+	// Long lines are folded by starting continuation lines with whitespace
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+		} else {
+			lines = append(lines, line)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return
+	}
+
+	var event *calendarEvent
+	var duration *time.Duration
+	depth := 0
+
+	for _, line := range lines {
+		name, params, value := parseICSLine(line)
+
+		switch name {
+		case "BEGIN":
+			if value == "VEVENT" {
+				event = &calendarEvent{}
+				duration = nil
+				depth = 0
+			} else if event != nil {
+				// Skip nested components such as VALARM
+				depth++
+			}
+			continue
+		case "END":
+			if event != nil && value == "VEVENT" {
+				if event.End.IsZero() {
+					switch {
+					case duration != nil:
+						event.End = event.Start.Add(*duration)
+					case event.AllDay:
+						event.End = event.Start.AddDate(0, 0, 1)
+					default:
+						event.End = event.Start
+					}
+				}
+				if !event.Start.IsZero() {
+					events = append(events, *event)
+				}
+				event = nil
+			} else if event != nil {
+				depth--
+			}
+			continue
+		}
+
+		if event == nil || depth > 0 {
+			continue
+		}
+
+		switch name {
+		case "UID":
+			event.UID = value
+		case "SUMMARY":
+			event.Summary = unescapeICSText(value)
+		case "CLASS":
+			event.Class = strings.ToUpper(value)
+		case "STATUS":
+			event.Status = strings.ToUpper(value)
+		case "TRANSP":
+			event.Transparent = strings.ToUpper(value) == "TRANSPARENT"
+		case "DTSTART":
+			if event.Start, event.AllDay, err = parseICSTime(value, params); err != nil {
+				return
+			}
+		case "DTEND":
+			if event.End, _, err = parseICSTime(value, params); err != nil {
+				return
+			}
+		case "DURATION":
+			var d time.Duration
+			if d, err = parseICSDuration(value); err != nil {
+				return
+			}
+			duration = &d
+		case "RRULE":
+			if rule, err := parseRecurrenceRule(value, params); err == nil {
+				event.Rule = rule
+			} else {
+				dlog.Printf("Ignoring recurrence rule for %s: %v", event.UID, err)
+			}
+		case "EXDATE":
+			for _, v := range strings.Split(value, ",") {
+				var t time.Time
+				if t, _, err = parseICSTime(v, params); err != nil {
+					return
+				}
+				event.ExDates = append(event.ExDates, t)
+			}
+		case "RECURRENCE-ID":
+			if event.RecurrenceID, _, err = parseICSTime(value, params); err != nil {
+				return
+			}
+		}
+	}
+
+	return
+}
+
+// parseICSLine splits a content line into its name, parameters and value
+func parseICSLine(line string) (name string, params map[string]string, value string) {
+	params = map[string]string{}
+
+	// The value starts at the first colon that isn't in a quoted parameter
+	quoted := false
+	sep := -1
+	for i, c := range line {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			sep = i
+			break
+		}
+	}
+	if sep == -1 {
+		return strings.ToUpper(line), params, ""
+	}
+
+	parts := strings.Split(line[:sep], ";")
+	name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+
+	return name, params, line[sep+1:]
+}
+
+func unescapeICSText(text string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(text)
+}
+
+// parseICSTime parses a DATE or DATE-TIME value. Times with a TZID are
+// interpreted in that zone, falling back to the local zone if it's unknown.
+func parseICSTime(value string, params map[string]string) (t time.Time, allDay bool, err error) {
+	loc := time.Local
+	if tzid, ok := params["TZID"]; ok {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		} else {
+			dlog.Printf("Unknown time zone %s, using local time", tzid)
+		}
+	}
+
+	switch {
+	case params["VALUE"] == "DATE" || len(value) == 8:
+		t, err = time.ParseInLocation("20060102", value, time.Local)
+		allDay = true
+	case strings.HasSuffix(value, "Z"):
+		t, err = time.Parse("20060102T150405Z", value)
+	default:
+		t, err = time.ParseInLocation("20060102T150405", value, loc)
+	}
+
+	return
+}
+
+// parseICSDuration parses a duration like "PT1H30M" or "P1D"
+func parseICSDuration(value string) (d time.Duration, err error) {
+	match := icsDuration.FindStringSubmatch(value)
+	if match == nil {
+		return 0, fmt.Errorf(`Invalid duration "%s"`, value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	for i, unit := range units {
+		if match[i+2] != "" {
+			n, _ := strconv.Atoi(match[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+
+	if match[1] == "-" {
+		d = -d
+	}
+	return
+}
+
+func parseRecurrenceRule(value string, params map[string]string) (rule *recurrenceRule, err error) {
+	rule = &recurrenceRule{Interval: 1}
+
+	for _, part := range strings.Split(value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch strings.ToUpper(kv[0]) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(kv[1])
+		case "INTERVAL":
+			if rule.Interval, err = strconv.Atoi(kv[1]); err != nil || rule.Interval < 1 {
+				return nil, fmt.Errorf(`Invalid interval "%s"`, kv[1])
+			}
+		case "COUNT":
+			if rule.Count, err = strconv.Atoi(kv[1]); err != nil {
+				return nil, fmt.Errorf(`Invalid count "%s"`, kv[1])
+			}
+		case "UNTIL":
+			if rule.Until, _, err = parseICSTime(kv[1], params); err != nil {
+				return
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(kv[1], ",") {
+				// BYDAY is only used by weekly rules; ordinal days like
+				// "1MO" are treated as plain weekdays
+				day = strings.TrimLeft(day, "+-0123456789")
+				found := false
+				for d := time.Sunday; d <= time.Saturday; d++ {
+					if strings.EqualFold(day, d.String()[:2]) {
+						rule.ByDay = append(rule.ByDay, d)
+						found = true
+					}
+				}
+				if !found {
+					return nil, fmt.Errorf(`Invalid day "%s"`, day)
+				}
+			}
+		}
+	}
+
+	switch rule.Freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf(`Unsupported frequency "%s"`, rule.Freq)
+	}
+
+	return
+}
+
+// Occurrences returns the occurrences of an event that overlap the range
+// [from, to)
+func (e *calendarEvent) Occurrences(from, to time.Time) (occurrences []eventOccurrence) {
+	length := e.End.Sub(e.Start)
+
+	add := func(start time.Time) {
+		for _, ex := range e.ExDates {
+			if ex.Equal(start) {
+				return
+			}
+		}
+		end := start.Add(length)
+		if start.Before(to) && end.After(from) {
+			occurrences = append(occurrences, eventOccurrence{Event: e, Start: start, End: end})
+		}
+	}
+
+	if e.Rule == nil {
+		add(e.Start)
+		return
+	}
+
+	rule := e.Rule
+	count := 0
+
+	for period := 0; period < maxRecurrences; period++ {
+		var starts []time.Time
+
+		switch rule.Freq {
+		case "DAILY":
+			starts = []time.Time{e.Start.AddDate(0, 0, period*rule.Interval)}
+		case "WEEKLY":
+			weekStart := e.Start.AddDate(0, 0, 7*period*rule.Interval)
+			if len(rule.ByDay) == 0 {
+				starts = []time.Time{weekStart}
+			} else {
+				// Weeks start on Monday
+				monday := weekStart.AddDate(0, 0, -((int(weekStart.Weekday()) + 6) % 7))
+				for d := 0; d < 7; d++ {
+					day := monday.AddDate(0, 0, d)
+					for _, wd := range rule.ByDay {
+						if day.Weekday() == wd && !day.Before(e.Start) {
+							starts = append(starts, day)
+						}
+					}
+				}
+			}
+		case "MONTHLY":
+			starts = []time.Time{e.Start.AddDate(0, period*rule.Interval, 0)}
+		case "YEARLY":
+			starts = []time.Time{e.Start.AddDate(period*rule.Interval, 0, 0)}
+		}
+
+		// Months without the start day (e.g., the 31st) are skipped rather
+		// than rolling over into the next month
+		if (rule.Freq == "MONTHLY" || rule.Freq == "YEARLY") && starts[0].Day() != e.Start.Day() {
+			continue
+		}
+
+		for _, start := range starts {
+			if !rule.Until.IsZero() && start.After(rule.Until) {
+				return
+			}
+			if rule.Count > 0 && count >= rule.Count {
+				return
+			}
+			if !start.Before(to) {
+				return
+			}
+			count++
+			add(start)
+		}
+	}
+
+	return
+}
+
+// eventOccurrences returns the occurrences of a set of events that overlap
+// the range [from, to). Occurrences that have been moved or changed, which
+// have their own event with a RECURRENCE-ID, are replaced by that event.
+func eventOccurrences(events []calendarEvent, from, to time.Time) (occurrences []eventOccurrence) {
+	overridden := map[string]bool{}
+	for _, e := range events {
+		if !e.RecurrenceID.IsZero() {
+			overridden[fmt.Sprintf("%s@%d", e.UID, e.RecurrenceID.Unix())] = true
+		}
+	}
+
+	for i := range events {
+		for _, o := range events[i].Occurrences(from, to) {
+			if events[i].RecurrenceID.IsZero() && overridden[fmt.Sprintf("%s@%d", o.Event.UID, o.Start.Unix())] {
+				continue
+			}
+			occurrences = append(occurrences, o)
+		}
+	}
+
+	return
+}
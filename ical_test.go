@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCalendar = `BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:standup
+SUMMARY:Daily standup
+DTSTART;TZID=America/New_York:20170501T093000
+DTEND;TZID=America/New_York:20170501T094500
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR
+EXDATE;TZID=America/New_York:20170510T093000
+BEGIN:VALARM
+SUMMARY:Not an event
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+SUMMARY:Late standup
+RECURRENCE-ID;TZID=America/New_York:20170512T093000
+DTSTART;TZID=America/New_York:20170512T110000
+DTEND;TZID=America/New_York:20170512T111500
+END:VEVENT
+BEGIN:VEVENT
+UID:doctor
+SUMMARY:Doctor\, annual
+  checkup
+CLASS:PRIVATE
+DTSTART:20170511T180000Z
+DURATION:PT1H
+END:VEVENT
+END:VCALENDAR
+`
+
+// TestParseICS tests parseICS and event recurrence
+func TestParseICS(t *testing.T) {
+	events, err := parseICS(strings.NewReader(testCalendar))
+	if err != nil {
+		t.Fatal("Error parsing calendar:", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+
+	doctor := events[2]
+	if doctor.Summary != "Doctor, annual checkup" {
+		t.Errorf("Unexpected summary %q", doctor.Summary)
+	}
+	if !doctor.End.Equal(time.Date(2017, time.May, 11, 19, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected end %v", doctor.End)
+	}
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("Time zone data isn't available")
+	}
+
+	from := time.Date(2017, time.May, 8, 0, 0, 0, 0, ny)
+	to := from.AddDate(0, 0, 7)
+
+	var starts []time.Time
+	for _, o := range eventOccurrences(events, from, to) {
+		if o.Event.UID == "standup" {
+			starts = append(starts, o.Start)
+		}
+	}
+
+	// Wednesday is excluded and Friday's meeting was moved
+	expected := []time.Time{
+		time.Date(2017, time.May, 8, 9, 30, 0, 0, ny),
+		time.Date(2017, time.May, 12, 11, 0, 0, 0, ny),
+	}
+	if len(starts) != len(expected) {
+		t.Fatalf("Expected occurrences at %v, got %v", expected, starts)
+	}
+	for i := range expected {
+		if !starts[i].Equal(expected[i]) {
+			t.Errorf("Expected occurrence at %v, got %v", expected[i], starts[i])
+		}
+	}
+
+	cal := CalendarConfig{Rules: []CalendarRule{{Match: "standup", Emoji: ":speaking_head_in_silhouette:"}}}
+	if text, emoji := cal.Status(&events[0]); text != "Daily standup" || emoji != ":speaking_head_in_silhouette:" {
+		t.Errorf("Unexpected status %q %q", text, emoji)
+	}
+	if text, emoji := cal.Status(&doctor); text != "Busy" || emoji != ":calendar:" {
+		t.Errorf("Unexpected private status %q %q", text, emoji)
+	}
+}
+
+// TestOccurrencesFrequencies tests that each kind of rule keeps recurring
+// past its first period
+func TestOccurrencesFrequencies(t *testing.T) {
+	// Tuesday
+	start := time.Date(2017, time.May, 2, 9, 0, 0, 0, time.UTC)
+	from := start
+	to := start.AddDate(0, 0, 14)
+
+	tests := []struct {
+		rule  string
+		count int
+	}{
+		{"FREQ=DAILY", 14},
+		{"FREQ=WEEKLY", 2},
+		{"FREQ=WEEKLY;BYDAY=TU", 2},
+		{"FREQ=WEEKLY;BYDAY=TU,TH", 4},
+		{"FREQ=MONTHLY", 1},
+	}
+
+	for _, test := range tests {
+		rule, err := parseRecurrenceRule(test.rule, nil)
+		if err != nil {
+			t.Fatalf("Error parsing %s: %v", test.rule, err)
+		}
+		event := calendarEvent{Start: start, End: start.Add(time.Hour), Rule: rule}
+		if n := len(event.Occurrences(from, to)); n != test.count {
+			t.Errorf("%s: expected %d occurrences, got %d", test.rule, test.count, n)
+		}
+	}
+
+	// Months without the 31st are skipped
+	start = time.Date(2017, time.January, 31, 9, 0, 0, 0, time.UTC)
+	rule, _ := parseRecurrenceRule("FREQ=MONTHLY", nil)
+	event := calendarEvent{Start: start, End: start.Add(time.Hour), Rule: rule}
+	if n := len(event.Occurrences(start, start.AddDate(0, 3, 0))); n != 2 {
+		t.Errorf("Expected 2 monthly occurrences, got %d", n)
+	}
+}
@@ -12,7 +12,12 @@ import (
 
 var cacheFile string
 var configFile string
+var focusFile string
+var historyFile string
+var scheduleFile string
+var usageFile string
 var emojiDir string
+var filesDir string
 var config configStruct
 var cache cacheStruct
 var workflow alfred.Workflow
@@ -25,19 +30,43 @@ const (
 
 	// AwayMarker marks the 'away' presence state
 	AwayMarker PresenceMarker = "○"
+
+	// DNDMarker marks that notifications are paused
+	DNDMarker PresenceMarker = "☾"
+
+	// FavoriteMarker marks favorite channels and users
+	FavoriteMarker PresenceMarker = "★"
 )
 
 type configStruct struct {
-	APIToken string `json:"api_key"`
+	APIToken  string           `json:"api_key"`
+	Presets   []StatusPreset   `json:"presets,omitempty"`
+	Focus     *FocusBundle     `json:"focus,omitempty"`
+	Calendars []CalendarConfig `json:"calendars,omitempty"`
+	// Favorites are the IDs of starred channels and users
+	Favorites []string `json:"favorites,omitempty"`
 }
 
 type cacheStruct struct {
 	Time         time.Time
 	Auth         Auth
 	PresenceTime time.Time
-	Channels     []Channel
-	Users        []User
-	Emoji        []Emoji
+	DND          DNDInfo
+	DNDTime      time.Time
+	Counts       map[string]ChannelCounts
+	CountsTime   time.Time
+	// CountsRefreshTime is when a background refresh of the counts started
+	CountsRefreshTime time.Time
+	// CountsProblem explains why some unread counts couldn't be fetched
+	CountsProblem string
+	// ChannelInfo holds recently fetched channel details, keyed by ID
+	ChannelInfo map[string]ChannelInfo
+	// CalendarEvent is the last calendar event occurrence used to set the
+	// status
+	CalendarEvent string
+	Channels      []Channel
+	Users         []User
+	Emoji         []Emoji
 }
 
 var dlog = log.New(os.Stderr, "[slack] ", log.LstdFlags)
@@ -54,10 +83,16 @@ func main() {
 	}
 
 	configFile = path.Join(workflow.DataDir(), "config.json")
+	focusFile = path.Join(workflow.DataDir(), "focus.json")
+	historyFile = path.Join(workflow.DataDir(), "history.json")
+	scheduleFile = path.Join(workflow.DataDir(), "schedule.json")
+	usageFile = path.Join(workflow.DataDir(), "usage.json")
 	cacheFile = path.Join(workflow.CacheDir(), "cache.json")
 	emojiDir = path.Join(workflow.CacheDir(), "emoji")
+	filesDir = path.Join(workflow.CacheDir(), "files")
 
 	os.MkdirAll(emojiDir, 0755)
+	os.MkdirAll(filesDir, 0755)
 
 	dlog.Println("Using config file", configFile)
 	dlog.Println("Using cache file", cacheFile)
@@ -72,13 +107,59 @@ func main() {
 	err = alfred.LoadJSON(cacheFile, &cache)
 	dlog.Println("loaded cache")
 
-	commands := []alfred.Command{
+	if len(os.Args) > 2 && os.Args[1] == "-focus-end" {
+		waitForFocusEnd(os.Args[2])
+		return
+	}
+
+	checkFocus()
+
+	// -tick is meant to be run periodically, such as by launchd, to apply
+	// scheduled status changes and calendar events
+	if len(os.Args) > 1 && os.Args[1] == "-tick" {
+		now := time.Now()
+		if err = applySchedule(now); err != nil {
+			dlog.Println("Error applying schedule:", err)
+			os.Exit(1)
+		}
+		if err = syncCalendars(now); err != nil {
+			dlog.Println("Error syncing calendars:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// -refresh-counts is run in the background by the channel list to update
+	// unread counts
+	if len(os.Args) > 1 && os.Args[1] == "-refresh-counts" {
+		if err = refreshCounts(); err != nil {
+			dlog.Println("Error refreshing unread counts:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Favorites are listed first in slk, above the commands
+	var commands []alfred.Command
+	for _, id := range config.Favorites {
+		commands = append(commands, favoriteCommand{ID: id})
+	}
+
+	commands = append(commands,
 		TokenCommand{},
 		ChannelsCommand{},
 		UsersCommand{},
+		SendCommand{},
+		SearchCommand{},
+		FilesCommand{},
 		StatusCommand{},
+		PresetsCommand{},
+		DNDCommand{},
+		FocusCommand{},
+		ScheduleCommand{},
+		FavoritesCommand{},
 		ResetCommand{},
-	}
+	)
 
 	workflow.Run(commands)
 }
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+// historyPageSize is the number of messages shown on each page of a channel's
+// history
+const historyPageSize = 20
+
+// channelHistoryItems returns the items for a page of a channel's history,
+// newest first. If latest is given, only messages before it are listed.
+func channelHistoryItems(cid, latest, arg string) (items []alfred.Item, err error) {
+	s := OpenSession(config.APIToken)
+
+	var messages []Message
+	var hasMore bool
+	if messages, hasMore, err = s.GetHistory(cid, latest, historyPageSize); err != nil {
+		return
+	}
+
+	now := time.Now()
+	for _, msg := range messages {
+		text, _ := renderMrkdwn(msg.Text)
+		if alfred.FuzzyMatches(text, arg) || alfred.FuzzyMatches(messageAuthor(msg), arg) {
+			item := messageItem(cid, msg, now)
+			addThreadMod(&item, cid, msg)
+			items = append(items, item)
+		}
+	}
+
+	if hasMore && len(messages) > 0 {
+		property := "history"
+		oldest := messages[len(messages)-1].Ts
+		items = append(items, alfred.Item{
+			Title:    "More…",
+			Subtitle: "Show older messages",
+			Arg: &alfred.ItemArg{
+				Keyword: "channels",
+				Data: alfred.Stringify(&channelConfig{
+					Channel:  &cid,
+					Property: &property,
+					Latest:   &oldest,
+				}),
+			},
+		})
+	}
+
+	return
+}
+
+// threadItems returns the items for a thread: the parent message followed by
+// its replies. If the query isn't empty, the first item posts it as a reply.
+func threadItems(cid, threadTs, arg string) (items []alfred.Item, err error) {
+	s := OpenSession(config.APIToken)
+
+	var messages []Message
+	if messages, err = s.GetReplies(cid, threadTs); err != nil {
+		return
+	}
+
+	if strings.TrimSpace(arg) != "" {
+		items = append(items, alfred.Item{
+			Title:    "Reply in thread",
+			Subtitle: arg,
+			Arg: &alfred.ItemArg{
+				Keyword: "send",
+				Mode:    alfred.ModeDo,
+				Data: alfred.Stringify(&sendConfig{
+					ToSend: &outgoingMessage{Channel: cid, Text: arg, ThreadTs: threadTs},
+				}),
+			},
+		})
+	}
+
+	now := time.Now()
+	for _, msg := range messages {
+		items = append(items, messageItem(cid, msg, now))
+	}
+
+	return
+}
+
+// addThreadMod lets a threaded message be expanded to show its replies
+func addThreadMod(item *alfred.Item, cid string, msg Message) {
+	if msg.ReplyCount == 0 {
+		return
+	}
+
+	property := "thread"
+	threadTs := msg.Ts
+	if msg.ThreadTs != "" {
+		threadTs = msg.ThreadTs
+	}
+
+	item.AddMod(alfred.ModCmd, alfred.ItemMod{
+		Subtitle: fmt.Sprintf("Show %d %s", msg.ReplyCount, plural(msg.ReplyCount, "reply", "replies")),
+		Arg: &alfred.ItemArg{
+			Keyword: "channels",
+			Data: alfred.Stringify(&channelConfig{
+				Channel:  &cid,
+				Property: &property,
+				ThreadTs: &threadTs,
+			}),
+		},
+	})
+}
+
+// messageItem returns an item for a message. Actioning it opens the message in
+// Slack.
+func messageItem(cid string, msg Message, now time.Time) alfred.Item {
+	text, _ := renderMrkdwn(msg.Text)
+
+	title := firstLine(text)
+	if title == "" && len(msg.Files) > 0 {
+		title = msg.Files[0].Title
+	}
+
+	subtitle := []string{messageAuthor(msg), formatAge(messageTime(msg.Ts), now)}
+	if msg.ReplyCount > 0 {
+		subtitle = append(subtitle, fmt.Sprintf("%d %s", msg.ReplyCount, plural(msg.ReplyCount, "reply", "replies")))
+	}
+	for _, file := range msg.Files {
+		subtitle = append(subtitle, fmt.Sprintf("📎 %s", file.Name))
+	}
+	for _, reaction := range msg.Reactions {
+		subtitle = append(subtitle, fmt.Sprintf(":%s: %d", reaction.Name, reaction.Count))
+	}
+
+	item := alfred.Item{
+		Title:    title,
+		Subtitle: strings.Join(subtitle, " · "),
+		Arg: &alfred.ItemArg{
+			Keyword: "channels",
+			Mode:    alfred.ModeDo,
+			Data: alfred.Stringify(&channelConfig{
+				ToOpenMessage: &messageID{Channel: cid, Ts: msg.Ts},
+			}),
+		},
+		Text: &alfred.ItemText{
+			Copy:      text,
+			LargeType: text,
+		},
+	}
+
+	item.AddMod(alfred.ModAlt, alfred.ItemMod{
+		Subtitle: "Copy the message text",
+		Arg: &alfred.ItemArg{
+			Keyword: "channels",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(&channelConfig{ToCopy: &text}),
+		},
+	})
+
+	return item
+}
+
+// messageAuthor returns the name of the user or bot that posted a message
+func messageAuthor(msg Message) string {
+	if user, found := getUser(msg.User); found {
+		return user.Name
+	}
+	if msg.Username != "" {
+		return msg.Username
+	}
+	return msg.User
+}
+
+// messageTime converts a message timestamp like "1503435956.000247" to a time
+func messageTime(ts string) time.Time {
+	seconds, _ := strconv.ParseFloat(ts, 64)
+	return time.Unix(int64(seconds), 0)
+}
+
+// formatAge describes how long ago t was, like "5m ago" or "Jan 2"
+func formatAge(t, now time.Time) string {
+	d := now.Sub(t)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case !t.Before(midnight):
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	case !t.Before(midnight.AddDate(0, 0, -1)):
+		return "yesterday at " + t.Format("15:04")
+	case t.Year() == now.Year():
+		return t.Format("Jan 2")
+	}
+	return t.Format("Jan 2, 2006")
+}
+
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
+
+func firstLine(text string) string {
+	if i := strings.Index(text, "\n"); i != -1 {
+		return text[:i] + "…"
+	}
+	return text
+}
+
+type messageID struct {
+	Channel string
+	Ts      string
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFormatAge tests formatAge
+func TestFormatAge(t *testing.T) {
+	now := time.Date(2017, time.May, 10, 14, 20, 0, 0, time.Local)
+
+	tests := []struct {
+		t        time.Time
+		expected string
+	}{
+		{now.Add(-30 * time.Second), "just now"},
+		{now.Add(-5 * time.Minute), "5m ago"},
+		{now.Add(-3 * time.Hour), "3h ago"},
+		{time.Date(2017, time.May, 9, 16, 5, 0, 0, time.Local), "yesterday at 16:05"},
+		{time.Date(2017, time.April, 2, 9, 0, 0, 0, time.Local), "Apr 2"},
+		{time.Date(2016, time.December, 24, 9, 0, 0, 0, time.Local), "Dec 24, 2016"},
+	}
+
+	for _, test := range tests {
+		if age := formatAge(test.t, now); age != test.expected {
+			t.Errorf("formatAge(%v): expected %q, got %q", test.t, test.expected, age)
+		}
+	}
+}
+
+// TestMessageTime tests messageTime
+func TestMessageTime(t *testing.T) {
+	if mt := messageTime("1503435956.000247"); mt.Unix() != 1503435956 {
+		t.Errorf("Expected 1503435956, got %d", mt.Unix())
+	}
+}
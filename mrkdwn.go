@@ -0,0 +1,86 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// mrkdwnEntity matches Slack's <...> escapes: mentions, links and special
+	// commands
+	mrkdwnEntity = regexp.MustCompile(`<([^<>|]*)(?:\|([^<>]*))?>`)
+
+	// Formatting markers only count at word boundaries, so snake_case and
+	// 2*3*4 are left alone
+	mrkdwnBold   = regexp.MustCompile(`(^|[^\w*])\*([^*\n]+)\*([^\w*]|$)`)
+	mrkdwnItalic = regexp.MustCompile(`(^|[^\w_])_([^_\n]+)_([^\w_]|$)`)
+	mrkdwnStrike = regexp.MustCompile(`(^|[^\w~])~([^~\n]+)~([^\w~]|$)`)
+	mrkdwnCode   = regexp.MustCompile("```\n?|`")
+	mrkdwnQuote  = regexp.MustCompile(`(?m)^(&gt;|>)\s?`)
+)
+
+// renderMrkdwn converts Slack message text to plain text. Mentions are
+// resolved from the cache, links are replaced by their labels and returned
+// separately, entities are decoded and formatting is removed.
+func renderMrkdwn(text string) (plain string, links []string) {
+	seen := map[string]bool{}
+
+	text = mrkdwnQuote.ReplaceAllString(text, "")
+
+	text = mrkdwnEntity.ReplaceAllStringFunc(text, func(match string) string {
+		parts := mrkdwnEntity.FindStringSubmatch(match)
+		target, label := parts[1], parts[2]
+
+		switch {
+		case strings.HasPrefix(target, "@"):
+			if user, found := getUser(target[1:]); found {
+				return "@" + user.Name
+			}
+			if label != "" {
+				return "@" + strings.TrimPrefix(label, "@")
+			}
+			return target
+
+		case strings.HasPrefix(target, "#"):
+			if channel, found := getChannel(target[1:]); found {
+				return "#" + channel.Name
+			}
+			if label != "" {
+				return "#" + label
+			}
+			return target
+
+		case strings.HasPrefix(target, "!"):
+			// Special commands like <!here>, <!subteam^ID|@team> or
+			// <!date^1392734382^{date}|Feb 18, 2014>
+			if label != "" {
+				return label
+			}
+			command := strings.SplitN(target[1:], "^", 2)[0]
+			return "@" + command
+		}
+
+		if !seen[target] {
+			seen[target] = true
+			links = append(links, decodeEntities(target))
+		}
+		if label != "" {
+			return label
+		}
+		return strings.TrimPrefix(target, "mailto:")
+	})
+
+	text = mrkdwnCode.ReplaceAllString(text, "")
+	for _, re := range []*regexp.Regexp{mrkdwnBold, mrkdwnItalic, mrkdwnStrike} {
+		// Run each pattern twice since adjacent matches share a boundary
+		text = re.ReplaceAllString(text, "$1$2$3")
+		text = re.ReplaceAllString(text, "$1$2$3")
+	}
+
+	return decodeEntities(text), links
+}
+
+// decodeEntities decodes the HTML entities Slack uses in message text
+func decodeEntities(text string) string {
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&").Replace(text)
+}
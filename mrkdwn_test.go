@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestRenderMrkdwn tests renderMrkdwn
+func TestRenderMrkdwn(t *testing.T) {
+	oldChannels, oldUsers := cache.Channels, cache.Users
+	defer func() { cache.Channels, cache.Users = oldChannels, oldUsers }()
+
+	cache.Channels = []Channel{{ID: "C123", Name: "general"}}
+	cache.Users = []User{{ID: "U024BE7LH", Name: "bob"}}
+
+	tests := []struct {
+		text  string
+		plain string
+		links []string
+	}{
+		{"hi <@U024BE7LH>", "hi @bob", nil},
+		{"hi <@U999|alice>", "hi @alice", nil},
+		{"see <#C123|old-name>", "see #general", nil},
+		{"<!here> lunch", "@here lunch", nil},
+		{"<!subteam^S1|@devs> ping", "@devs ping", nil},
+		{"Q&amp;A &lt;today&gt;", "Q&A <today>", nil},
+		{"docs at <https://x.com/a?b=1&amp;c=2|the wiki>", "docs at the wiki", []string{"https://x.com/a?b=1&c=2"}},
+		{"<https://a.com> and <https://b.com|b> and <https://a.com>", "https://a.com and b and https://a.com", []string{"https://a.com", "https://b.com"}},
+		{"mail <mailto:bob@example.com|bob@example.com>", "mail bob@example.com", []string{"mailto:bob@example.com"}},
+		{"*bold* _italic_ ~gone~ `code`", "bold italic gone code", nil},
+		{"snake_case_name and 2*3*4", "snake_case_name and 2*3*4", nil},
+		{"&gt; quoted\nreply", "quoted\nreply", nil},
+	}
+
+	for _, test := range tests {
+		plain, links := renderMrkdwn(test.text)
+		if plain != test.plain {
+			t.Errorf("%q: expected %q, got %q", test.text, test.plain, plain)
+		}
+		if !reflect.DeepEqual(links, test.links) {
+			t.Errorf("%q: expected links %v, got %v", test.text, test.links, links)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+// StatusPreset is a named status that can be applied in one step
+type StatusPreset struct {
+	Name     string   `json:"name"`
+	Text     string   `json:"text"`
+	Emoji    string   `json:"emoji"`
+	Presence Presence `json:"presence,omitempty"`
+	Duration string   `json:"duration,omitempty"`
+}
+
+// Description returns a short summary of what a preset will set
+func (p *StatusPreset) Description() string {
+	var parts []string
+
+	if p.Text != "" {
+		parts = append(parts, fmt.Sprintf("“%s”", p.Text))
+	} else {
+		parts = append(parts, "No status message")
+	}
+
+	if p.Emoji != "" {
+		parts = append(parts, p.Emoji)
+	}
+
+	if p.Presence != "" {
+		parts = append(parts, string(p.Presence))
+	}
+
+	if p.Duration != "" {
+		if _, _, ok := parseStatusExpiration(p.Duration, time.Now()); ok {
+			parts = append(parts, p.Duration)
+		} else {
+			parts = append(parts, "for "+p.Duration)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// Expiration returns the time at which a status set from the preset now
+// should expire, or the zero time if it shouldn't expire
+func (p *StatusPreset) Expiration() (expiration time.Time, err error) {
+	if p.Duration == "" {
+		return
+	}
+
+	return parseExpiration(p.Duration, time.Now())
+}
+
+// PresetsCommand manages status presets
+type PresetsCommand struct{}
+
+// About returns information about a command
+func (c PresetsCommand) About() alfred.CommandDef {
+	return alfred.CommandDef{
+		Keyword:     "presets",
+		Description: "Manage status presets",
+		IsEnabled:   config.APIToken != "",
+		Arg: &alfred.ItemArg{
+			Keyword: "presets",
+		},
+	}
+}
+
+// Items returns the items for the command
+func (c PresetsCommand) Items(arg, data string) (items []alfred.Item, err error) {
+	var cfg presetConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			dlog.Printf("Invalid preset config")
+		}
+	}
+
+	if cfg.Preset != nil {
+		i := indexOfPreset(*cfg.Preset)
+		if i == -1 {
+			err = fmt.Errorf(`Unknown preset "%s"`, *cfg.Preset)
+			return
+		}
+		return presetPropertyItems(config.Presets[i], arg), nil
+	}
+
+	for _, preset := range config.Presets {
+		if alfred.FuzzyMatches(preset.Name, arg) {
+			name := preset.Name
+
+			item := alfred.Item{
+				Title:        name,
+				Subtitle:     preset.Description(),
+				Autocomplete: name,
+				Arg: &alfred.ItemArg{
+					Keyword: "presets",
+					Data:    alfred.Stringify(&presetConfig{Preset: &name}),
+				},
+			}
+
+			if preset.Emoji != "" {
+				if emojiFile, err := getEmojiIcon(preset.Emoji); err == nil {
+					item.Icon = emojiFile
+				}
+			}
+
+			item.AddMod(alfred.ModAlt, alfred.ItemMod{
+				Subtitle: "Delete this preset",
+				Arg: &alfred.ItemArg{
+					Keyword: "presets",
+					Mode:    alfred.ModeDo,
+					Data:    alfred.Stringify(&presetConfig{ToDelete: &name}),
+				},
+			})
+
+			items = append(items, item)
+		}
+	}
+
+	alfred.FuzzySort(items, arg)
+
+	if arg != "" {
+		subtitle := "Save your current status as a new preset"
+		if indexOfPreset(arg) != -1 {
+			subtitle = "Replace this preset with your current status"
+		}
+
+		items = append(items, alfred.Item{
+			Title:    fmt.Sprintf("Save as “%s”", arg),
+			Subtitle: subtitle,
+			Arg: &alfred.ItemArg{
+				Keyword: "presets",
+				Mode:    alfred.ModeDo,
+				Data:    alfred.Stringify(&presetConfig{ToCreate: &arg}),
+			},
+		})
+	}
+
+	return
+}
+
+// Do implements the command
+func (c PresetsCommand) Do(data string) (out string, err error) {
+	var cfg presetConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			return "", fmt.Errorf("Error unmarshalling data: %v", err)
+		}
+	}
+
+	if cfg.ToCreate != nil {
+		i := indexOfUserByID(cache.Auth.UserID)
+		if i == -1 {
+			return "", fmt.Errorf("The user cache is empty")
+		}
+
+		user := cache.Users[i]
+		preset := StatusPreset{
+			Name:     *cfg.ToCreate,
+			Text:     user.Profile.StatusText,
+			Emoji:    user.Profile.StatusEmoji,
+			Presence: user.Presence,
+		}
+
+		if p := indexOfPreset(preset.Name); p != -1 {
+			config.Presets[p] = preset
+		} else {
+			config.Presets = append(config.Presets, preset)
+		}
+
+		if err = alfred.SaveJSON(configFile, &config); err != nil {
+			return
+		}
+		out = fmt.Sprintf("Saved preset %s", preset.Name)
+	}
+
+	if cfg.ToDelete != nil {
+		i := indexOfPreset(*cfg.ToDelete)
+		if i == -1 {
+			return "", fmt.Errorf(`Unknown preset "%s"`, *cfg.ToDelete)
+		}
+
+		config.Presets = append(config.Presets[:i], config.Presets[i+1:]...)
+		if err = alfred.SaveJSON(configFile, &config); err != nil {
+			return
+		}
+		out = fmt.Sprintf("Deleted preset %s", *cfg.ToDelete)
+	}
+
+	if cfg.ToUpdate != nil {
+		i := indexOfPreset(cfg.ToUpdate.Preset)
+		if i == -1 {
+			return "", fmt.Errorf(`Unknown preset "%s"`, cfg.ToUpdate.Preset)
+		}
+
+		preset := config.Presets[i]
+		value := strings.TrimSpace(cfg.ToUpdate.Value)
+
+		switch cfg.ToUpdate.Property {
+		case "name":
+			if value == "" {
+				return "", fmt.Errorf("A preset name is required")
+			}
+			if j := indexOfPreset(value); j != -1 && j != i {
+				return "", fmt.Errorf(`A preset named "%s" already exists`, value)
+			}
+			preset.Name = value
+		case "text":
+			preset.Text = value
+		case "emoji":
+			if value != "" {
+				value = ":" + strings.Trim(value, ":") + ":"
+			}
+			preset.Emoji = value
+		case "presence":
+			switch Presence(strings.ToLower(value)) {
+			case PresenceActive, PresenceAway, "":
+				preset.Presence = Presence(strings.ToLower(value))
+			default:
+				return "", fmt.Errorf(`Presence must be "active" or "away"`)
+			}
+		case "duration":
+			if value != "" {
+				if _, err = parseExpiration(value, time.Now()); err != nil {
+					return "", fmt.Errorf(`Invalid duration "%s"`, value)
+				}
+			}
+			preset.Duration = value
+		default:
+			return "", fmt.Errorf(`Unknown preset property "%s"`, cfg.ToUpdate.Property)
+		}
+
+		config.Presets[i] = preset
+		if err = alfred.SaveJSON(configFile, &config); err != nil {
+			return
+		}
+		out = fmt.Sprintf("Updated preset %s", preset.Name)
+	}
+
+	return
+}
+
+// presetPropertyItems returns the editable properties of a preset. The query
+// is the new value for whichever property is actioned.
+func presetPropertyItems(preset StatusPreset, arg string) (items []alfred.Item) {
+	properties := []struct {
+		name  string
+		title string
+		value string
+	}{
+		{"name", "Name", preset.Name},
+		{"text", "Text", preset.Text},
+		{"emoji", "Emoji", preset.Emoji},
+		{"presence", "Presence", string(preset.Presence)},
+		{"duration", "Duration", preset.Duration},
+	}
+
+	for _, prop := range properties {
+		var subtitle string
+		if arg == "" {
+			subtitle = fmt.Sprintf("Clear the %s", prop.name)
+		} else {
+			subtitle = fmt.Sprintf("Set the %s to “%s”", prop.name, arg)
+		}
+
+		items = append(items, alfred.Item{
+			UID:          fmt.Sprintf("%s.presets.%s", workflow.BundleID(), prop.name),
+			Title:        fmt.Sprintf("%s: %s", prop.title, prop.value),
+			Subtitle:     subtitle,
+			Autocomplete: prop.value,
+			Arg: &alfred.ItemArg{
+				Keyword: "presets",
+				Mode:    alfred.ModeDo,
+				Data: alfred.Stringify(&presetConfig{
+					ToUpdate: &presetUpdate{
+						Preset:   preset.Name,
+						Property: prop.name,
+						Value:    arg,
+					},
+				}),
+			},
+		})
+	}
+
+	name := preset.Name
+	items = append(items, alfred.Item{
+		UID:      fmt.Sprintf("%s.presets.delete", workflow.BundleID()),
+		Title:    "Delete",
+		Subtitle: "Delete this preset",
+		Arg: &alfred.ItemArg{
+			Keyword: "presets",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(&presetConfig{ToDelete: &name}),
+		},
+	})
+
+	return
+}
+
+func indexOfPreset(name string) int {
+	for i := range config.Presets {
+		if strings.EqualFold(config.Presets[i].Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+type presetUpdate struct {
+	Preset   string
+	Property string
+	Value    string
+}
+
+type presetConfig struct {
+	Preset   *string
+	ToCreate *string
+	ToDelete *string
+	ToUpdate *presetUpdate
+}
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+var emojiToken = regexp.MustCompile(`^:[\w+-]+:$`)
+
+// ScheduleEntry is a status change applied at the same time on certain days
+type ScheduleEntry struct {
+	Days        string   `json:"days"`
+	Start       string   `json:"start"`
+	End         string   `json:"end,omitempty"`
+	Text        string   `json:"text"`
+	Emoji       string   `json:"emoji,omitempty"`
+	Presence    Presence `json:"presence,omitempty"`
+	LastApplied string   `json:"last_applied,omitempty"`
+}
+
+// parseScheduleEntry parses a schedule entry from a query like "weekdays
+// 08:00-08:45 Commuting :train: +away". The days default to every day, and
+// the end time, emoji and presence are optional.
+func parseScheduleEntry(query string) (entry ScheduleEntry, err error) {
+	fields := strings.Fields(query)
+
+	entry.Days = "daily"
+	if len(fields) > 0 {
+		if _, err := parseDays(fields[0]); err == nil {
+			entry.Days = strings.ToLower(fields[0])
+			fields = fields[1:]
+		}
+	}
+
+	if len(fields) == 0 {
+		return entry, fmt.Errorf("Missing start time")
+	}
+
+	times := strings.SplitN(strings.ToLower(fields[0]), "-", 2)
+	if entry.Start, err = normalizeClockTime(times[0]); err != nil {
+		return
+	}
+	if len(times) > 1 {
+		if entry.End, err = normalizeClockTime(times[1]); err != nil {
+			return
+		}
+	}
+
+	var words []string
+	for _, field := range fields[1:] {
+		switch {
+		case field == "+away":
+			entry.Presence = PresenceAway
+		case field == "+active":
+			entry.Presence = PresenceActive
+		case emojiToken.MatchString(field):
+			entry.Emoji = field
+		default:
+			words = append(words, field)
+		}
+	}
+	entry.Text = strings.Join(words, " ")
+
+	if entry.Text == "" && entry.Emoji == "" {
+		return entry, fmt.Errorf("Missing status")
+	}
+
+	return
+}
+
+// Description returns a short description of when the entry applies
+func (e *ScheduleEntry) Description() string {
+	days := "Daily"
+	if e.Days != "" {
+		days = strings.ToUpper(e.Days[:1]) + e.Days[1:]
+	}
+	if e.End != "" {
+		return fmt.Sprintf("%s %s–%s", days, e.Start, e.End)
+	}
+	return fmt.Sprintf("%s at %s", days, e.Start)
+}
+
+// Times returns the start and end of the entry on the day of t. If the entry
+// has no end time, end is zero.
+func (e *ScheduleEntry) Times(t time.Time) (start, end time.Time, err error) {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	var hour, minute int
+	if hour, minute, err = parseClockTime(e.Start); err != nil {
+		return
+	}
+	start = midnight.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+
+	if e.End != "" {
+		if hour, minute, err = parseClockTime(e.End); err != nil {
+			return
+		}
+		end = midnight.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+		if !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+	}
+
+	return
+}
+
+// Window returns the start and end of the entry's window that contains now,
+// if there is one. An end at or before the start wraps past midnight, so an
+// early morning time may be in the window that started the day before.
+func (e *ScheduleEntry) Window(now time.Time) (start, end time.Time, found bool) {
+	days, err := parseDays(e.Days)
+	if err != nil {
+		return
+	}
+
+	for i, day := range []time.Time{now, now.AddDate(0, 0, -1)} {
+		if !days[day.Weekday()] {
+			continue
+		}
+
+		var from, to time.Time
+		if from, to, err = e.Times(day); err != nil {
+			return
+		}
+
+		// Only a window with an end can reach into the next day
+		if i == 1 && to.IsZero() {
+			break
+		}
+
+		if !now.Before(from) && (to.IsZero() || now.Before(to)) {
+			return from, to, true
+		}
+	}
+
+	return
+}
+
+// IsDue returns true if the entry should be applied at the given time. An
+// entry is due once on each of its days, between its start and end times.
+func (e *ScheduleEntry) IsDue(now time.Time) bool {
+	start, _, found := e.Window(now)
+	return found && e.LastApplied != start.Format("2006-01-02")
+}
+
+// parseDays parses a day specification like "daily", "weekdays", "weekends",
+// "mon,wed,fri" or "mon-thu"
+func parseDays(spec string) (days [7]bool, err error) {
+	spec = strings.ToLower(spec)
+
+	switch spec {
+	case "daily":
+		return [7]bool{true, true, true, true, true, true, true}, nil
+	case "weekdays":
+		return [7]bool{false, true, true, true, true, true, false}, nil
+	case "weekends":
+		return [7]bool{true, false, false, false, false, false, true}, nil
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+
+		first, found := parseWeekday(bounds[0])
+		if !found {
+			return days, fmt.Errorf(`Invalid day "%s"`, bounds[0])
+		}
+
+		last := first
+		if len(bounds) > 1 {
+			if last, found = parseWeekday(bounds[1]); !found {
+				return days, fmt.Errorf(`Invalid day "%s"`, bounds[1])
+			}
+		}
+
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+
+	return
+}
+
+func normalizeClockTime(text string) (normalized string, err error) {
+	var hour, minute int
+	if hour, minute, err = parseClockTime(text); err != nil {
+		return
+	}
+	return fmt.Sprintf("%02d:%02d", hour%24, minute), nil
+}
+
+func loadSchedule() (schedule []ScheduleEntry) {
+	if !fileExists(scheduleFile) {
+		return
+	}
+	if err := alfred.LoadJSON(scheduleFile, &schedule); err != nil {
+		dlog.Println("Error loading schedule:", err)
+	}
+
+	// The schedule may have been edited by hand; entries without days apply
+	// every day
+	for i := range schedule {
+		if strings.TrimSpace(schedule[i].Days) == "" {
+			schedule[i].Days = "daily"
+		}
+	}
+	return
+}
+
+// applySchedule applies any schedule entries that are due. Each entry is
+// applied at most once per day.
+func applySchedule(now time.Time) (err error) {
+	schedule := loadSchedule()
+
+	var due []int
+	for i := range schedule {
+		if schedule[i].IsDue(now) {
+			due = append(due, i)
+		}
+	}
+
+	if len(due) == 0 {
+		return
+	}
+
+	// Apply entries in the order they started so the latest one wins
+	sort.SliceStable(due, func(a, b int) bool {
+		return schedule[due[a]].Start < schedule[due[b]].Start
+	})
+
+	s := OpenSession(config.APIToken)
+	ui := indexOfUserByID(cache.Auth.UserID)
+
+	for _, i := range due {
+		entry := &schedule[i]
+		start, end, _ := entry.Window(now)
+
+		dlog.Printf("Applying schedule entry %s", entry.Description())
+
+		if err = s.SetStatus(entry.Text, entry.Emoji, end); err != nil {
+			return
+		}
+		if ui != -1 {
+			cache.Users[ui].Profile.StatusText = entry.Text
+			cache.Users[ui].Profile.StatusEmoji = entry.Emoji
+			cache.Users[ui].Profile.StatusExpiration = 0
+			if !end.IsZero() {
+				cache.Users[ui].Profile.StatusExpiration = end.Unix()
+			}
+		}
+
+		if entry.Presence != "" {
+			if err = s.SetPresence(entry.Presence); err != nil {
+				return
+			}
+			if ui != -1 {
+				cache.Users[ui].Presence = entry.Presence
+			}
+		}
+
+		entry.LastApplied = start.Format("2006-01-02")
+		if err = alfred.SaveJSON(scheduleFile, &schedule); err != nil {
+			return
+		}
+	}
+
+	return alfred.SaveJSON(cacheFile, &cache)
+}
+
+// ScheduleCommand manages scheduled status changes
+type ScheduleCommand struct{}
+
+// About returns information about a command
+func (c ScheduleCommand) About() alfred.CommandDef {
+	return alfred.CommandDef{
+		Keyword:     "schedule",
+		Description: "Schedule status changes",
+		IsEnabled:   config.APIToken != "",
+		Arg: &alfred.ItemArg{
+			Keyword: "schedule",
+		},
+	}
+}
+
+// Items returns a list of filter items
+func (c ScheduleCommand) Items(arg, data string) (items []alfred.Item, err error) {
+	for i, entry := range loadSchedule() {
+		title := strings.TrimSpace(fmt.Sprintf("%s %s", entry.Text, entry.Emoji))
+		if !alfred.FuzzyMatches(title, arg) && !alfred.FuzzyMatches(entry.Description(), arg) {
+			continue
+		}
+
+		subtitle := entry.Description()
+		if entry.Presence != "" {
+			subtitle += ", " + string(entry.Presence)
+		}
+
+		index := i
+		item := alfred.Item{
+			Title:    title,
+			Subtitle: subtitle,
+		}
+
+		if entry.Emoji != "" {
+			if emojiFile, err := getEmojiIcon(entry.Emoji); err == nil {
+				item.Icon = emojiFile
+			}
+		}
+
+		item.AddMod(alfred.ModAlt, alfred.ItemMod{
+			Subtitle: "Remove this entry",
+			Arg: &alfred.ItemArg{
+				Keyword: "schedule",
+				Mode:    alfred.ModeDo,
+				Data:    alfred.Stringify(scheduleConfig{ToRemove: &index}),
+			},
+		})
+
+		items = append(items, item)
+	}
+
+	alfred.FuzzySort(items, arg)
+
+	if strings.TrimSpace(arg) != "" {
+		if entry, err := parseScheduleEntry(arg); err == nil {
+			subtitle := entry.Description()
+			if entry.Presence != "" {
+				subtitle += ", " + string(entry.Presence)
+			}
+
+			item := alfred.Item{
+				Title:    strings.TrimSpace(fmt.Sprintf("Add “%s” %s", entry.Text, entry.Emoji)),
+				Subtitle: subtitle,
+				Arg: &alfred.ItemArg{
+					Keyword: "schedule",
+					Mode:    alfred.ModeDo,
+					Data:    alfred.Stringify(scheduleConfig{ToAdd: &entry}),
+				},
+			}
+			items = append([]alfred.Item{item}, items...)
+		} else {
+			items = append(items, alfred.Item{
+				Title:    "Add an entry like “weekdays 08:00-08:45 Commuting :train:”",
+				Subtitle: err.Error(),
+			})
+		}
+	}
+
+	return
+}
+
+// Do implements the command
+func (c ScheduleCommand) Do(data string) (out string, err error) {
+	var cfg scheduleConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			return "", fmt.Errorf("Error unmarshalling data: %v", err)
+		}
+	}
+
+	schedule := loadSchedule()
+
+	if cfg.ToAdd != nil {
+		schedule = append(schedule, *cfg.ToAdd)
+		out = fmt.Sprintf("Scheduled %s", cfg.ToAdd.Description())
+	}
+
+	if cfg.ToRemove != nil {
+		i := *cfg.ToRemove
+		if i < 0 || i >= len(schedule) {
+			return "", fmt.Errorf("Invalid schedule entry")
+		}
+		out = fmt.Sprintf("Removed %s", schedule[i].Description())
+		schedule = append(schedule[:i], schedule[i+1:]...)
+	}
+
+	err = alfred.SaveJSON(scheduleFile, &schedule)
+	return
+}
+
+type scheduleConfig struct {
+	ToAdd    *ScheduleEntry
+	ToRemove *int
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// TestParseScheduleEntry tests parseScheduleEntry
+func TestParseScheduleEntry(t *testing.T) {
+	entry, err := parseScheduleEntry("weekdays 8am-08:45 Commuting :train: +away")
+	if err != nil {
+		t.Fatal("Error parsing entry:", err)
+	}
+
+	expected := ScheduleEntry{
+		Days:     "weekdays",
+		Start:    "08:00",
+		End:      "08:45",
+		Text:     "Commuting",
+		Emoji:    ":train:",
+		Presence: PresenceAway,
+	}
+	if entry != expected {
+		t.Errorf("Expected %#v, got %#v", expected, entry)
+	}
+
+	entry, err = parseScheduleEntry("12:00 Lunch")
+	if err != nil {
+		t.Fatal("Error parsing entry:", err)
+	}
+	if entry.Days != "daily" || entry.Start != "12:00" || entry.End != "" || entry.Text != "Lunch" {
+		t.Errorf("Unexpected entry %#v", entry)
+	}
+
+	if _, err = parseScheduleEntry("weekdays Lunch"); err == nil {
+		t.Error("Expected an error for a missing time")
+	}
+}
+
+// TestScheduleEntryIsDue tests ScheduleEntry.IsDue
+func TestScheduleEntryIsDue(t *testing.T) {
+	entry := ScheduleEntry{Days: "mon-fri", Start: "08:00", End: "08:45", Text: "Commuting"}
+
+	// Wednesday
+	day := time.Date(2017, time.May, 10, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		time time.Time
+		due  bool
+	}{
+		{day.Add(7*time.Hour + 59*time.Minute), false},
+		{day.Add(8 * time.Hour), true},
+		{day.Add(8*time.Hour + 30*time.Minute), true},
+		{day.Add(8*time.Hour + 45*time.Minute), false},
+		{day.AddDate(0, 0, 3).Add(8*time.Hour + 30*time.Minute), false},
+	}
+
+	for _, test := range tests {
+		if due := entry.IsDue(test.time); due != test.due {
+			t.Errorf("%v: expected due to be %v", test.time, test.due)
+		}
+	}
+
+	entry.LastApplied = day.Format("2006-01-02")
+	if entry.IsDue(day.Add(8 * time.Hour)) {
+		t.Error("Entry should only be due once per day")
+	}
+}
+
+// TestScheduleEntryIsDueOvernight tests an entry whose window crosses
+// midnight
+func TestScheduleEntryIsDueOvernight(t *testing.T) {
+	entry := ScheduleEntry{Days: "mon-fri", Start: "22:00", End: "07:00", Text: "Sleeping"}
+
+	// Friday
+	day := time.Date(2017, time.May, 12, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		time time.Time
+		due  bool
+	}{
+		{day.Add(21*time.Hour + 59*time.Minute), false},
+		{day.Add(22 * time.Hour), true},
+		{day.Add(23*time.Hour + 30*time.Minute), true},
+		// Saturday morning is still in Friday's window
+		{day.AddDate(0, 0, 1).Add(6 * time.Hour), true},
+		{day.AddDate(0, 0, 1).Add(7 * time.Hour), false},
+		// Friday morning is in Thursday's window
+		{day.Add(2 * time.Hour), true},
+		// Monday morning would be in Sunday's window, which isn't scheduled
+		{day.AddDate(0, 0, 3).Add(2 * time.Hour), false},
+	}
+
+	for _, test := range tests {
+		if due := entry.IsDue(test.time); due != test.due {
+			t.Errorf("%v: expected due to be %v", test.time, test.due)
+		}
+	}
+
+	// Applying Friday's window after midnight still counts for Friday
+	entry.LastApplied = day.Format("2006-01-02")
+	if entry.IsDue(day.AddDate(0, 0, 1).Add(6 * time.Hour)) {
+		t.Error("Entry should only be due once per window")
+	}
+}
+
+// TestLoadScheduleMissingDays tests that hand-edited entries without days
+// apply daily
+func TestLoadScheduleMissingDays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schedule")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldScheduleFile := scheduleFile
+	scheduleFile = path.Join(dir, "schedule.json")
+	defer func() { scheduleFile = oldScheduleFile }()
+
+	data := `[{"start": "12:00", "text": "Lunch"}, {"days": "", "start": "18:00", "text": "Gone"}]`
+	if err = ioutil.WriteFile(scheduleFile, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	schedule := loadSchedule()
+	if len(schedule) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(schedule))
+	}
+	for _, entry := range schedule {
+		if entry.Days != "daily" {
+			t.Errorf("Expected days to be daily, got %q", entry.Days)
+		}
+	}
+	if d := schedule[0].Description(); d != "Daily at 12:00" {
+		t.Errorf("Unexpected description %q", d)
+	}
+
+	var empty ScheduleEntry
+	if d := empty.Description(); d != "Daily at " {
+		t.Errorf("Unexpected description %q", d)
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+	"github.com/pkg/browser"
+)
+
+// searchModifiers are the search modifiers suggested for an empty query
+var searchModifiers = []struct {
+	modifier    string
+	description string
+}{
+	{"in:#", "Messages in a channel"},
+	{"from:@", "Messages from a user"},
+	{"before:", "Messages before a date, like 2017-05-10 or yesterday"},
+	{"after:", "Messages after a date, like 2017-05-10 or yesterday"},
+	{"has:link", "Messages containing links"},
+}
+
+// SearchCommand searches for messages
+type SearchCommand struct{}
+
+// About returns information about a command
+func (c SearchCommand) About() alfred.CommandDef {
+	return alfred.CommandDef{
+		Keyword:     "search",
+		Description: "Search for messages",
+		IsEnabled:   config.APIToken != "",
+		Arg: &alfred.ItemArg{
+			Keyword: "search",
+		},
+	}
+}
+
+// Items returns the items for the command
+func (c SearchCommand) Items(arg, data string) (items []alfred.Item, err error) {
+	if err = checkRefresh(); err != nil {
+		return
+	}
+
+	var cfg searchConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			dlog.Printf("Invalid search config")
+		}
+	}
+
+	query := arg
+	page := 1
+	if query == "" && cfg.Query != nil {
+		query = *cfg.Query
+		page = cfg.Page
+	}
+
+	if strings.TrimSpace(query) == "" {
+		for _, m := range searchModifiers {
+			items = append(items, alfred.Item{
+				Title:        m.modifier,
+				Subtitle:     m.description,
+				Autocomplete: m.modifier,
+			})
+		}
+		return
+	}
+
+	if completions, complete := searchCompletions(query); !complete {
+		return completions, nil
+	}
+
+	s := OpenSession(config.APIToken)
+
+	var matches []SearchMatch
+	var pages int
+	if matches, pages, err = s.SearchMessages(query, page); err != nil {
+		return
+	}
+
+	if len(matches) == 0 {
+		items = append(items, alfred.Item{
+			Title:    "No messages found",
+			Subtitle: query,
+		})
+		return
+	}
+
+	now := time.Now()
+	for _, match := range matches {
+		items = append(items, searchMatchItem(match, now))
+	}
+
+	if page < pages {
+		items = append(items, alfred.Item{
+			Title:    "More…",
+			Subtitle: fmt.Sprintf("Show page %d of %d", page+1, pages),
+			Arg: &alfred.ItemArg{
+				Keyword: "search",
+				Data:    alfred.Stringify(&searchConfig{Query: &query, Page: page + 1}),
+			},
+		})
+	}
+
+	return
+}
+
+// Do implements the command
+func (c SearchCommand) Do(data string) (out string, err error) {
+	var cfg searchConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			return "", fmt.Errorf("Error unmarshalling data: %v", err)
+		}
+	}
+
+	if cfg.ToOpen != nil {
+		err = browser.OpenURL(*cfg.ToOpen)
+	}
+
+	return
+}
+
+// searchCompletions returns completions for an in: or from: modifier at the
+// end of a query. If the modifier is complete, or there isn't one, complete is
+// true and the query can be searched.
+func searchCompletions(query string) (items []alfred.Item, complete bool) {
+	if strings.HasSuffix(query, " ") {
+		return nil, true
+	}
+
+	prefix := ""
+	token := query
+	if i := strings.LastIndex(query, " "); i != -1 {
+		prefix = query[:i+1]
+		token = query[i+1:]
+	}
+
+	var modifier, name string
+	var users bool
+	switch {
+	case strings.HasPrefix(token, "in:@"):
+		modifier, name, users = "in:@", token[4:], true
+	case strings.HasPrefix(token, "in:"):
+		modifier, name = "in:#", strings.TrimPrefix(token[3:], "#")
+	case strings.HasPrefix(token, "from:"):
+		modifier, name, users = "from:@", strings.TrimPrefix(token[5:], "@"), true
+	default:
+		return nil, true
+	}
+
+	if users {
+		if _, found := getUserByName(name); found {
+			return nil, true
+		}
+		for _, user := range cache.Users {
+			if !user.Deleted && alfred.FuzzyMatches(user.Name, name) {
+				items = append(items, alfred.Item{
+					Title:        modifier + user.Name,
+					Subtitle:     user.Profile.RealName,
+					Autocomplete: prefix + modifier + user.Name + " ",
+				})
+			}
+		}
+	} else {
+		if _, found := getChannelByName(name); found {
+			return nil, true
+		}
+		for _, channel := range cache.Channels {
+			if alfred.FuzzyMatches(channel.Name, name) {
+				items = append(items, alfred.Item{
+					Title:        modifier + channel.Name,
+					Subtitle:     channel.Purpose.Value,
+					Autocomplete: prefix + modifier + channel.Name + " ",
+				})
+			}
+		}
+	}
+
+	alfred.FuzzySort(items, name)
+	return items, false
+}
+
+// searchMatchItem returns an item for a search result. Actioning it opens the
+// message in Slack.
+func searchMatchItem(match SearchMatch, now time.Time) alfred.Item {
+	where := "#" + match.Channel.Name
+	if user, found := getUser(match.Channel.Name); found {
+		where = "@" + user.Name
+	}
+
+	subtitle := []string{messageAuthor(match.Message), where, formatAge(messageTime(match.Ts), now)}
+
+	text, _ := renderMrkdwn(match.Text)
+
+	permalink := match.Permalink
+	return alfred.Item{
+		Title:    firstLine(text),
+		Subtitle: strings.Join(subtitle, " · "),
+		Arg: &alfred.ItemArg{
+			Keyword: "search",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(&searchConfig{ToOpen: &permalink}),
+		},
+		Text: &alfred.ItemText{
+			Copy:      text,
+			LargeType: text,
+		},
+	}
+}
+
+type searchConfig struct {
+	Query  *string
+	Page   int
+	ToOpen *string
+}
@@ -0,0 +1,36 @@
+package main
+
+import "testing"
+
+// TestSearchCompletions tests searchCompletions
+func TestSearchCompletions(t *testing.T) {
+	oldChannels, oldUsers := cache.Channels, cache.Users
+	defer func() { cache.Channels, cache.Users = oldChannels, oldUsers }()
+
+	cache.Channels = []Channel{{ID: "C1", Name: "general"}, {ID: "C2", Name: "random"}}
+	cache.Users = []User{{ID: "U1", Name: "bob"}}
+
+	tests := []struct {
+		query        string
+		complete     bool
+		autocomplete string
+	}{
+		{"deploy", true, ""},
+		{"deploy in:#general", true, ""},
+		{"deploy in:gen", false, "deploy in:#general "},
+		{"deploy from:b", false, "deploy from:@bob "},
+		{"in:@bo", false, "in:@bob "},
+		{"deploy in:gen ", true, ""},
+	}
+
+	for _, test := range tests {
+		items, complete := searchCompletions(test.query)
+		if complete != test.complete {
+			t.Errorf("%q: expected complete to be %v", test.query, test.complete)
+			continue
+		}
+		if test.autocomplete != "" && (len(items) == 0 || items[0].Autocomplete != test.autocomplete) {
+			t.Errorf("%q: expected completion %q, got %v", test.query, test.autocomplete, items)
+		}
+	}
+}
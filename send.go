@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+var mentionMatcher = regexp.MustCompile(`(^|[\s(])([@#])([\w.\-]+)`)
+
+// SendCommand sends messages to channels
+type SendCommand struct{}
+
+// About returns information about a command
+func (c SendCommand) About() alfred.CommandDef {
+	return alfred.CommandDef{
+		Keyword:     "send",
+		Description: "Send a message to a channel",
+		IsEnabled:   config.APIToken != "",
+		Arg: &alfred.ItemArg{
+			Keyword: "send",
+		},
+	}
+}
+
+// Items returns the items for the command
+func (c SendCommand) Items(arg, data string) (items []alfred.Item, err error) {
+	if err = checkRefresh(); err != nil {
+		return
+	}
+
+	var cfg sendConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			dlog.Printf("Invalid send config")
+		}
+	}
+
+	if cfg.Channel != nil {
+		return sendItems(*cfg.Channel, arg), nil
+	}
+
+	// The query should look like "#channel some message"
+	if !strings.HasPrefix(arg, "#") {
+		items = append(items, alfred.Item{
+			Title:        "Enter a #channel and a message",
+			Autocomplete: "#",
+		})
+		return
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(arg, "#"), " ", 2)
+	if len(parts) == 1 {
+		for _, channel := range cache.Channels {
+			if alfred.FuzzyMatches(channel.Name, parts[0]) {
+				items = append(items, alfred.Item{
+					Title:        "#" + channel.Name,
+					Subtitle:     channel.Purpose.Value,
+					Autocomplete: "#" + channel.Name + " ",
+				})
+			}
+		}
+		alfred.FuzzySort(items, parts[0])
+		return
+	}
+
+	channel, found := getChannelByName(parts[0])
+	if !found {
+		items = append(items, alfred.Item{
+			Title:        fmt.Sprintf("Unknown channel #%s", parts[0]),
+			Autocomplete: "#",
+		})
+		return
+	}
+
+	return sendItems(channel.ID, parts[1]), nil
+}
+
+// Do implements the command
+func (c SendCommand) Do(data string) (out string, err error) {
+	var cfg sendConfig
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+			return "", fmt.Errorf("Error unmarshalling data: %v", err)
+		}
+	}
+
+	if cfg.ToSend != nil {
+		return sendMessage(*cfg.ToSend)
+	}
+
+	return
+}
+
+// sendMessage posts a message and returns its permalink
+func sendMessage(msg outgoingMessage) (permalink string, err error) {
+	s := OpenSession(config.APIToken)
+
+	// Direct messages are sent to users whose conversation may not be open
+	if msg.Channel == "" {
+		if msg.Channel, err = s.OpenConversation(msg.Users); err != nil {
+			return
+		}
+	}
+
+	threadTs := msg.ThreadTs
+	if msg.ToLatestThread {
+		var messages []Message
+		if messages, _, err = s.GetHistory(msg.Channel, "", 1); err != nil {
+			return
+		}
+		if len(messages) == 0 {
+			return "", fmt.Errorf("There are no messages to reply to")
+		}
+		threadTs = messages[0].Ts
+		if messages[0].ThreadTs != "" {
+			threadTs = messages[0].ThreadTs
+		}
+	}
+
+	recipients := msg.Users
+	if len(recipients) == 0 {
+		recipients = []string{msg.Channel}
+	}
+	if err := recordUsage(recipients...); err != nil {
+		dlog.Printf("Error recording usage: %v", err)
+	}
+
+	var ts string
+	if ts, err = s.PostMessage(msg.Channel, resolveMentions(msg.Text), threadTs); err != nil {
+		return
+	}
+
+	return s.GetPermalink(msg.Channel, ts)
+}
+
+// sendItems returns the items for sending a message to a channel
+func sendItems(cid, text string) (items []alfred.Item) {
+	name := cid
+	if channel, found := getChannel(cid); found {
+		name = "#" + channel.Name
+	}
+
+	if strings.TrimSpace(text) == "" {
+		items = append(items, alfred.Item{
+			Title:    fmt.Sprintf("Type a message to send to %s", name),
+			Subtitle: "Use @name and #channel to mention users and channels",
+		})
+		return
+	}
+
+	items = append(items, sendItem(name, outgoingMessage{Channel: cid, Text: text}))
+	return
+}
+
+// sendItem returns an item that sends a message to the named recipient
+func sendItem(name string, msg outgoingMessage) alfred.Item {
+	item := alfred.Item{
+		Title:    fmt.Sprintf("Send to %s", name),
+		Subtitle: msg.Text,
+		Arg: &alfred.ItemArg{
+			Keyword: "send",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(&sendConfig{ToSend: &msg}),
+		},
+	}
+
+	threaded := msg
+	threaded.ToLatestThread = true
+	item.AddMod(alfred.ModCmd, alfred.ItemMod{
+		Subtitle: "Reply in the thread of the latest message",
+		Arg: &alfred.ItemArg{
+			Keyword: "send",
+			Mode:    alfred.ModeDo,
+			Data:    alfred.Stringify(&sendConfig{ToSend: &threaded}),
+		},
+	})
+
+	return item
+}
+
+// resolveMentions escapes a message and converts its @user and #channel
+// references into Slack's mention syntax. Unknown names are left alone.
+func resolveMentions(text string) string {
+	text = escapeMrkdwn(text)
+
+	return mentionMatcher.ReplaceAllStringFunc(text, func(match string) string {
+		parts := mentionMatcher.FindStringSubmatch(match)
+		prefix, sigil, name := parts[1], parts[2], parts[3]
+
+		// Punctuation ending a sentence, like "ping @alice.", isn't part of
+		// the name
+		trimmed := strings.TrimRight(name, ".-")
+		suffix := name[len(trimmed):]
+		name = trimmed
+
+		if sigil == "@" {
+			switch name {
+			case "here", "channel", "everyone":
+				return fmt.Sprintf("%s<!%s>%s", prefix, name, suffix)
+			}
+			if user, found := getUserByName(name); found {
+				return fmt.Sprintf("%s<@%s>%s", prefix, user.ID, suffix)
+			}
+		} else if channel, found := getChannelByName(name); found {
+			return fmt.Sprintf("%s<#%s|%s>%s", prefix, channel.ID, channel.Name, suffix)
+		}
+
+		return match
+	})
+}
+
+// escapeMrkdwn escapes the characters Slack treats as control characters in
+// message text
+func escapeMrkdwn(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+type outgoingMessage struct {
+	Channel        string
+	Users          []string
+	Text           string
+	ThreadTs       string
+	ToLatestThread bool
+}
+
+type sendConfig struct {
+	Channel *string
+	ToSend  *outgoingMessage
+}
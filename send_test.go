@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+// TestResolveMentions tests resolveMentions
+func TestResolveMentions(t *testing.T) {
+	oldChannels, oldUsers := cache.Channels, cache.Users
+	defer func() { cache.Channels, cache.Users = oldChannels, oldUsers }()
+
+	cache.Users = []User{{ID: "U1", Name: "alice"}, {ID: "U2", Name: "bob.smith"}}
+	cache.Channels = []Channel{{ID: "C1", Name: "general"}}
+
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"ping @alice.", "ping <@U1>."},
+		{"@bob.smith, see #general...", "<@U2>, see <#C1|general>..."},
+		{"(@here) hi", "(<!here>) hi"},
+		{"@carol is unknown", "@carol is unknown"},
+		{"if a < b && b > c @alice", "if a &lt; b &amp;&amp; b &gt; c <@U1>"},
+	}
+
+	for _, test := range tests {
+		if text := resolveMentions(test.text); text != test.expected {
+			t.Errorf("resolveMentions(%q) = %q, expected %q", test.text, text, test.expected)
+		}
+	}
+}
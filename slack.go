@@ -3,12 +3,16 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"path"
+	"strconv"
+	"strings"
+	"time"
 
 	alfred "github.com/jason0x43/go-alfred"
 )
@@ -19,6 +23,10 @@ import (
 
 var client = &http.Client{}
 
+// errCountsUnsupported is returned when the API token can't be used to read
+// unread counts
+var errCountsUnsupported = errors.New("Unread counts aren't available for this API token")
+
 // Session represents an active connection to the Slack REST API.
 type Session struct {
 	APIToken string
@@ -49,10 +57,17 @@ type Auth struct {
 
 // Channel represents a channel
 type Channel struct {
-	ID      string   `json:"id"`
-	Name    string   `json:"name"`
-	Members []string `json:"members"`
-	Topic   struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Members     []string `json:"members"`
+	Created     int64    `json:"created"`
+	Creator     string   `json:"creator"`
+	NumMembers  int      `json:"num_members"`
+	IsPrivate   bool     `json:"is_private"`
+	IsShared    bool     `json:"is_shared"`
+	IsExtShared bool     `json:"is_ext_shared"`
+	IsArchived  bool     `json:"is_archived"`
+	Topic       struct {
 		Value   string `json:"value"`
 		Creator string `json:"creator"`
 	} `json:"topic"`
@@ -70,32 +85,63 @@ type Emoji struct {
 
 // PinnedMessage is a pinned message
 type PinnedMessage struct {
-	Ts        string   `json:"ts"`
-	Type      string   `json:"type"`
-	Permalink string   `json:"permalink"`
-	User      string   `json:"user"`
-	Text      string   `json:"text"`
-	PinnedTo  []string `json:"pinned_to"`
-}
-
-// PinnedFile is a pinned file
-type PinnedFile struct {
-	Channels   []string `json:"channels"`
-	FileType   string   `json:"filetype"`
-	MimeType   string   `json:"mimetype"`
+	Ts         string   `json:"ts"`
+	Type       string   `json:"type"`
 	Permalink  string   `json:"permalink"`
-	PrettyType string   `json:"pretty_type"`
-	Thumb64    string   `json:"thumb_64"`
-	Title      string   `json:"title"`
-	PrivateURL string   `json:"url_private"`
+	User       string   `json:"user"`
+	Text       string   `json:"text"`
+	PinnedTo   []string `json:"pinned_to"`
+	ThreadTs   string   `json:"thread_ts"`
+	ReplyCount int      `json:"reply_count"`
+}
+
+// File is a file shared in Slack
+type File struct {
+	ID          string   `json:"id"`
+	Created     int64    `json:"created"`
+	User        string   `json:"user"`
+	Channels    []string `json:"channels"`
+	Groups      []string `json:"groups"`
+	IMs         []string `json:"ims"`
+	FileType    string   `json:"filetype"`
+	MimeType    string   `json:"mimetype"`
+	Mode        string   `json:"mode"`
+	Permalink   string   `json:"permalink"`
+	PrettyType  string   `json:"pretty_type"`
+	Size        int64    `json:"size"`
+	Thumb64     string   `json:"thumb_64"`
+	Thumb360    string   `json:"thumb_360"`
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	PrivateURL  string   `json:"url_private"`
+	DownloadURL string   `json:"url_private_download"`
 }
 
 // Pin represents a pinned item
 type Pin struct {
-	Channel string         `json:"channel"`
-	Created int64          `json:"created"`
-	Message *PinnedMessage `json:"message,omitempty"`
-	File    *PinnedFile    `json:"file,omitempty"`
+	Channel   string         `json:"channel"`
+	Created   int64          `json:"created"`
+	CreatedBy string         `json:"created_by"`
+	Message   *PinnedMessage `json:"message,omitempty"`
+	File      *File          `json:"file,omitempty"`
+}
+
+// ChannelCounts are the unread counts for a channel the user is a member of
+type ChannelCounts struct {
+	ID          string `json:"id"`
+	HasUnreads  bool   `json:"has_unreads"`
+	UnreadCount int    `json:"unread_count_display"`
+	LastRead    string `json:"last_read"`
+}
+
+// Bookmark is a link bookmarked in a channel
+type Bookmark struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	Title     string `json:"title"`
+	Link      string `json:"link"`
+	Emoji     string `json:"emoji"`
+	Type      string `json:"type"`
 }
 
 // User is a Slack user
@@ -104,22 +150,108 @@ type User struct {
 	Name    string `json:"name"`
 	Deleted bool   `json:"deleted"`
 	Profile struct {
-		FirstName   string `json:"first_name"`
-		LastName    string `json:"last_name"`
-		RealName    string `json:"real_name"`
-		Email       string `json:"email"`
-		StatusText  string `json:"status_text"`
-		StatusEmoji string `json:"status_emoji"`
+		FirstName        string `json:"first_name"`
+		LastName         string `json:"last_name"`
+		RealName         string `json:"real_name"`
+		Email            string `json:"email"`
+		StatusText       string `json:"status_text"`
+		StatusEmoji      string `json:"status_emoji"`
+		StatusExpiration int64  `json:"status_expiration"`
 	} `json:"profile"`
 	Presence Presence `json:"presence"`
 }
 
-// Title returns the title of a Pin
+// Message is a message in a channel
+type Message struct {
+	Type       string     `json:"type"`
+	Subtype    string     `json:"subtype"`
+	User       string     `json:"user"`
+	Username   string     `json:"username"`
+	Text       string     `json:"text"`
+	Ts         string     `json:"ts"`
+	ThreadTs   string     `json:"thread_ts"`
+	ReplyCount int        `json:"reply_count"`
+	Files      []File     `json:"files"`
+	Reactions  []Reaction `json:"reactions"`
+}
+
+// SearchMatch is a message found by a search
+type SearchMatch struct {
+	Message
+	Permalink string `json:"permalink"`
+	Channel   struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"channel"`
+}
+
+// Reaction is an emoji reaction to a message
+type Reaction struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Users []string `json:"users"`
+}
+
+// DNDInfo is the Do Not Disturb state of a user
+type DNDInfo struct {
+	Enabled         bool  `json:"dnd_enabled"`
+	NextStart       int64 `json:"next_dnd_start_ts"`
+	NextEnd         int64 `json:"next_dnd_end_ts"`
+	SnoozeEnabled   bool  `json:"snooze_enabled"`
+	SnoozeEndTime   int64 `json:"snooze_endtime"`
+	SnoozeRemaining int64 `json:"snooze_remaining"`
+}
+
+// IsActive returns true if notifications are paused, either by a snooze or by
+// the user's regular Do Not Disturb schedule
+func (d *DNDInfo) IsActive(now time.Time) bool {
+	return d.Snoozed(now) || d.Scheduled(now)
+}
+
+// Snoozed returns true if notifications have been snoozed
+func (d *DNDInfo) Snoozed(now time.Time) bool {
+	return d.SnoozeEnabled && time.Unix(d.SnoozeEndTime, 0).After(now)
+}
+
+// Scheduled returns true if the user's Do Not Disturb schedule is in effect
+func (d *DNDInfo) Scheduled(now time.Time) bool {
+	return d.Enabled && d.NextStart != 0 && !now.Before(time.Unix(d.NextStart, 0)) &&
+		now.Before(time.Unix(d.NextEnd, 0))
+}
+
+// Until returns the time at which paused notifications will resume
+func (d *DNDInfo) Until(now time.Time) time.Time {
+	var until time.Time
+	if d.Snoozed(now) {
+		until = time.Unix(d.SnoozeEndTime, 0)
+	}
+	if d.Scheduled(now) && time.Unix(d.NextEnd, 0).After(until) {
+		until = time.Unix(d.NextEnd, 0)
+	}
+	return until
+}
+
+// Title returns the title of a Pin. Pins of other kinds, like file comments,
+// have no title.
 func (p *Pin) Title() string {
 	if p.Message != nil {
 		return p.Message.Text
 	}
-	return p.File.Title
+	if p.File != nil {
+		return p.File.Title
+	}
+	return ""
+}
+
+// Permalink returns a link to a pinned item, or "" if there isn't one
+func (p *Pin) Permalink() string {
+	if p.Message != nil {
+		return p.Message.Permalink
+	}
+	if p.File != nil {
+		return p.File.Permalink
+	}
+	return ""
 }
 
 // OpenSession opens a session using an existing API token.
@@ -170,6 +302,37 @@ func (session *Session) GetChannels() (channels []Channel, err error) {
 	return response.Channels, nil
 }
 
+// GetChannelInfo returns up-to-date information about a channel
+func (session *Session) GetChannelInfo(channelID string) (channel Channel, err error) {
+	params := map[string]string{
+		"token":               session.APIToken,
+		"channel":             channelID,
+		"include_num_members": "1",
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "conversations.info", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok      bool    `json:"ok"`
+		Error   string  `json:"error"`
+		Channel Channel `json:"channel"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return channel, fmt.Errorf("Unable to get channel info: %s", response.Error)
+	}
+
+	return response.Channel, nil
+}
+
 // GetEmoji returns the list of custom emoji for the current team
 func (session *Session) GetEmoji() (emoji []Emoji, err error) {
 	params := map[string]string{
@@ -227,6 +390,118 @@ func (session *Session) GetPins(channelID string) (pins []Pin, err error) {
 	return
 }
 
+// RemovePin unpins a message or file from a channel
+func (session *Session) RemovePin(channelID, ts, fileID string) (err error) {
+	params := map[string]string{
+		"channel": channelID,
+	}
+	if ts != "" {
+		params["timestamp"] = ts
+	}
+	if fileID != "" {
+		params["file"] = fileID
+	}
+	return session.call("pins.remove", params, "unpin")
+}
+
+// GetBookmarks returns the bookmarks in a channel
+func (session *Session) GetBookmarks(channelID string) (bookmarks []Bookmark, err error) {
+	params := map[string]string{
+		"token":      session.APIToken,
+		"channel_id": channelID,
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "bookmarks.list", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok        bool       `json:"ok"`
+		Error     string     `json:"error"`
+		Bookmarks []Bookmark `json:"bookmarks"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return nil, fmt.Errorf("Unable to get bookmarks: %s", response.Error)
+	}
+
+	return response.Bookmarks, nil
+}
+
+// AddBookmark bookmarks a link in a channel
+func (session *Session) AddBookmark(channelID, title, link string) (err error) {
+	params := map[string]string{
+		"channel_id": channelID,
+		"title":      title,
+		"type":       "link",
+		"link":       link,
+	}
+	return session.call("bookmarks.add", params, "add bookmark")
+}
+
+// GetUnreadCount returns the unread count and last read timestamp of a
+// channel the user is a member of. Only user tokens can see the user's read
+// state; errCountsUnsupported is returned for other tokens.
+func (session *Session) GetUnreadCount(channelID string) (counts ChannelCounts, err error) {
+	params := map[string]string{
+		"token":   session.APIToken,
+		"channel": channelID,
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "conversations.info", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok      bool   `json:"ok"`
+		Error   string `json:"error"`
+		Channel struct {
+			ID                 string  `json:"id"`
+			LastRead           *string `json:"last_read"`
+			UnreadCountDisplay int     `json:"unread_count_display"`
+		} `json:"channel"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		if isUnsupportedError(response.Error) {
+			return counts, errCountsUnsupported
+		}
+		return counts, fmt.Errorf("Unable to get unread count: %s", response.Error)
+	}
+
+	if response.Channel.LastRead == nil {
+		return counts, errCountsUnsupported
+	}
+
+	return ChannelCounts{
+		ID:          response.Channel.ID,
+		HasUnreads:  response.Channel.UnreadCountDisplay > 0,
+		UnreadCount: response.Channel.UnreadCountDisplay,
+		LastRead:    *response.Channel.LastRead,
+	}, nil
+}
+
+// MarkChannel moves the read cursor in a channel to the given message
+func (session *Session) MarkChannel(channelID, ts string) (err error) {
+	params := map[string]string{
+		"channel": channelID,
+		"ts":      ts,
+	}
+	return session.call("conversations.mark", params, "mark channel as read")
+}
+
 // GetUsers for a channel
 func (session *Session) GetUsers() (users []User, err error) {
 	params := map[string]string{
@@ -312,21 +587,27 @@ func (session *Session) SetPresence(presence Presence) (err error) {
 	return
 }
 
-// SetStatus updates a user's status
-func (session *Session) SetStatus(text, emoji string) (err error) {
+// SetStatus updates a user's status. A zero expiration means the status
+// doesn't expire.
+func (session *Session) SetStatus(text, emoji string, expiration time.Time) (err error) {
+	var expires int64
+	if !expiration.IsZero() {
+		expires = expiration.Unix()
+	}
+
+	profile := alfred.Stringify(map[string]interface{}{
+		"status_text":       text,
+		"status_emoji":      emoji,
+		"status_expiration": expires,
+	})
+
 	params := map[string]string{
-		"token": session.APIToken,
-		"profile": alfred.Stringify(map[string]string{
-			"status_text":  text,
-			"status_emoji": emoji,
-		}),
+		"token":   session.APIToken,
+		"profile": profile,
 	}
 
 	data := map[string]string{
-		"profile": alfred.Stringify(map[string]string{
-			"status_text":  text,
-			"status_emoji": emoji,
-		}),
+		"profile": profile,
 	}
 
 	var rdata []byte
@@ -385,6 +666,482 @@ func (session *Session) OpenDirectMessage(userID string) (channelID string, err
 	return response.Channel.ID, nil
 }
 
+// OpenConversation opens a direct message channel with one or more users and
+// returns the channel ID. Several users get a group DM.
+func (session *Session) OpenConversation(userIDs []string) (channelID string, err error) {
+	params := map[string]string{
+		"token":     session.APIToken,
+		"users":     strings.Join(userIDs, ","),
+		"return_im": "true",
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "conversations.open", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok      bool `json:"ok"`
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+		Error string `json:"error"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return "", fmt.Errorf("Unable to open conversation: %s", response.Error)
+	}
+
+	return response.Channel.ID, nil
+}
+
+// GetDNDInfo returns the Do Not Disturb state of the authenticated user
+func (session *Session) GetDNDInfo() (info DNDInfo, err error) {
+	params := map[string]string{
+		"token": session.APIToken,
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "dnd.info", params); err != nil {
+		return
+	}
+
+	var response struct {
+		DNDInfo
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return info, fmt.Errorf("Unable to get DND info: %s", response.Error)
+	}
+
+	return response.DNDInfo, nil
+}
+
+// SetSnooze snoozes notifications for the given number of minutes
+func (session *Session) SetSnooze(minutes int) (endTime int64, err error) {
+	params := map[string]string{
+		"token":       session.APIToken,
+		"num_minutes": strconv.Itoa(minutes),
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "dnd.setSnooze", params); err != nil {
+		return
+	}
+
+	dlog.Printf("response: %s", data)
+
+	var response struct {
+		Ok            bool   `json:"ok"`
+		Error         string `json:"error"`
+		SnoozeEndTime int64  `json:"snooze_endtime"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return 0, fmt.Errorf("Unable to snooze notifications: %s", response.Error)
+	}
+
+	return response.SnoozeEndTime, nil
+}
+
+// JoinChannel adds the authenticated user to a channel
+func (session *Session) JoinChannel(channelID string) (err error) {
+	return session.call("conversations.join", map[string]string{"channel": channelID}, "join channel")
+}
+
+// LeaveChannel removes the authenticated user from a channel
+func (session *Session) LeaveChannel(channelID string) (err error) {
+	return session.call("conversations.leave", map[string]string{"channel": channelID}, "leave channel")
+}
+
+// CreateChannel creates a public or private channel and returns it
+func (session *Session) CreateChannel(name string, private bool) (channel Channel, err error) {
+	params := map[string]string{
+		"token":      session.APIToken,
+		"name":       name,
+		"is_private": strconv.FormatBool(private),
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "conversations.create", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok      bool    `json:"ok"`
+		Error   string  `json:"error"`
+		Channel Channel `json:"channel"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return channel, fmt.Errorf("Unable to create channel: %s", response.Error)
+	}
+
+	return response.Channel, nil
+}
+
+// InviteToChannel adds users to a channel
+func (session *Session) InviteToChannel(channelID string, userIDs []string) (err error) {
+	params := map[string]string{"channel": channelID, "users": strings.Join(userIDs, ",")}
+	return session.call("conversations.invite", params, "invite users")
+}
+
+// KickFromChannel removes a user from a channel
+func (session *Session) KickFromChannel(channelID, userID string) (err error) {
+	params := map[string]string{"channel": channelID, "user": userID}
+	return session.call("conversations.kick", params, "remove user")
+}
+
+// SetChannelTopic sets the topic of a channel
+func (session *Session) SetChannelTopic(channelID, topic string) (err error) {
+	params := map[string]string{"channel": channelID, "topic": topic}
+	return session.call("conversations.setTopic", params, "set topic")
+}
+
+// SetChannelPurpose sets the purpose of a channel
+func (session *Session) SetChannelPurpose(channelID, purpose string) (err error) {
+	params := map[string]string{"channel": channelID, "purpose": purpose}
+	return session.call("conversations.setPurpose", params, "set purpose")
+}
+
+// GetHistory returns up to limit messages from a channel, newest first. If
+// latest is given, only messages before that timestamp are returned.
+func (session *Session) GetHistory(channelID, latest string, limit int) (messages []Message, hasMore bool, err error) {
+	params := map[string]string{
+		"token":   session.APIToken,
+		"channel": channelID,
+		"limit":   strconv.Itoa(limit),
+	}
+	if latest != "" {
+		params["latest"] = latest
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "conversations.history", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok       bool      `json:"ok"`
+		Error    string    `json:"error"`
+		Messages []Message `json:"messages"`
+		HasMore  bool      `json:"has_more"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return nil, false, fmt.Errorf("Unable to get history: %s", response.Error)
+	}
+
+	return response.Messages, response.HasMore, nil
+}
+
+// GetReplies returns the messages in a thread, starting with the parent
+// message
+func (session *Session) GetReplies(channelID, threadTs string) (messages []Message, err error) {
+	params := map[string]string{
+		"token":   session.APIToken,
+		"channel": channelID,
+		"ts":      threadTs,
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "conversations.replies", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok       bool      `json:"ok"`
+		Error    string    `json:"error"`
+		Messages []Message `json:"messages"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return nil, fmt.Errorf("Unable to get replies: %s", response.Error)
+	}
+
+	return response.Messages, nil
+}
+
+// PostMessage posts a message to a channel and returns its timestamp. If
+// threadTs is given, the message is posted as a reply in that thread.
+func (session *Session) PostMessage(channelID, text, threadTs string) (ts string, err error) {
+	params := map[string]string{
+		"token":   session.APIToken,
+		"channel": channelID,
+		"text":    text,
+		"as_user": "true",
+		"mrkdwn":  "true",
+	}
+	if threadTs != "" {
+		params["thread_ts"] = threadTs
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "chat.postMessage", params); err != nil {
+		return
+	}
+
+	dlog.Printf("response: %s", data)
+
+	var response struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+		Ts    string `json:"ts"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return "", fmt.Errorf("Unable to post message: %s", response.Error)
+	}
+
+	return response.Ts, nil
+}
+
+// GetPermalink returns a link to a message
+func (session *Session) GetPermalink(channelID, ts string) (permalink string, err error) {
+	params := map[string]string{
+		"token":      session.APIToken,
+		"channel":    channelID,
+		"message_ts": ts,
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "chat.getPermalink", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok        bool   `json:"ok"`
+		Error     string `json:"error"`
+		Permalink string `json:"permalink"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return "", fmt.Errorf("Unable to get permalink: %s", response.Error)
+	}
+
+	return response.Permalink, nil
+}
+
+// SearchMessages searches for messages matching a query, which may use
+// Slack's search modifiers. Pages are numbered from 1.
+func (session *Session) SearchMessages(query string, page int) (matches []SearchMatch, pages int, err error) {
+	params := map[string]string{
+		"token": session.APIToken,
+		"query": query,
+		"count": "20",
+		"page":  strconv.Itoa(page),
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "search.messages", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok       bool   `json:"ok"`
+		Error    string `json:"error"`
+		Messages struct {
+			Matches []SearchMatch `json:"matches"`
+			Paging  struct {
+				Page  int `json:"page"`
+				Pages int `json:"pages"`
+			} `json:"paging"`
+		} `json:"messages"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return nil, 0, fmt.Errorf("Unable to search messages: %s", response.Error)
+	}
+
+	return response.Messages.Matches, response.Messages.Paging.Pages, nil
+}
+
+// ListFiles lists files, newest first. The channel, user and types filters
+// are optional; types is a comma-separated list like "images,pdfs".
+func (session *Session) ListFiles(channelID, userID, types string) (files []File, err error) {
+	params := map[string]string{
+		"token": session.APIToken,
+		"count": "50",
+	}
+	if channelID != "" {
+		params["channel"] = channelID
+	}
+	if userID != "" {
+		params["user"] = userID
+	}
+	if types != "" {
+		params["types"] = types
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "files.list", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+		Files []File `json:"files"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return nil, fmt.Errorf("Unable to list files: %s", response.Error)
+	}
+
+	return response.Files, nil
+}
+
+// SearchFiles searches for files matching a query, which may use Slack's
+// search modifiers
+func (session *Session) SearchFiles(query string) (files []File, err error) {
+	params := map[string]string{
+		"token": session.APIToken,
+		"query": query,
+		"count": "50",
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, "search.files", params); err != nil {
+		return
+	}
+
+	var response struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+		Files struct {
+			Matches []File `json:"matches"`
+		} `json:"files"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return nil, fmt.Errorf("Unable to search files: %s", response.Error)
+	}
+
+	return response.Files.Matches, nil
+}
+
+// EndSnooze ends the current notification snooze
+func (session *Session) EndSnooze() (err error) {
+	return session.call("dnd.endSnooze", nil, "end snooze")
+}
+
+// EndDND ends the current Do Not Disturb session
+func (session *Session) EndDND() (err error) {
+	return session.call("dnd.endDnd", nil, "end Do Not Disturb")
+}
+
+// apiError is an error reported by the Slack API. Code is Slack's error code,
+// like "not_in_channel".
+type apiError struct {
+	Code        string
+	Description string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("Unable to %s: %s", e.Description, e.Code)
+}
+
+// isUnsupportedError returns true if an API error means the method can't be
+// used with the current token
+func isUnsupportedError(code string) bool {
+	switch code {
+	case "unknown_method", "not_allowed_token_type", "method_deprecated":
+		return true
+	}
+	return false
+}
+
+// call makes an API request that returns nothing but a status. The
+// description is used in the error message if the request fails.
+func (session *Session) call(method string, params map[string]string, description string) (err error) {
+	values := map[string]string{
+		"token": session.APIToken,
+	}
+	for key, value := range params {
+		values[key] = value
+	}
+
+	var data []byte
+	if data, err = session.get(slackAPI, method, values); err != nil {
+		return
+	}
+
+	dlog.Printf("response: %s", data)
+
+	var response struct {
+		Ok    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err = dec.Decode(&response); err != nil {
+		return
+	}
+
+	if !response.Ok {
+		return &apiError{Code: response.Error, Description: description}
+	}
+
+	return
+}
+
 func (session *Session) request(method string, requestURL string, body io.Reader) ([]byte, error) {
 	req, err := http.NewRequest(method, requestURL, body)
 	req.Header.Add("Content-Type", "application/json")
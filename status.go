@@ -54,7 +54,7 @@ func (c StatusCommand) Items(arg, data string) (items []alfred.Item, err error)
 					Arg: &alfred.ItemArg{
 						Keyword: "status",
 						Mode:    alfred.ModeDo,
-						Data:    alfred.Stringify(statusConfig{NewState: cfg.NewState, StatusText: cfg.StatusText, StatusEmoji: &ename}),
+						Data:    alfred.Stringify(statusConfig{NewState: cfg.NewState, StatusText: cfg.StatusText, StatusEmoji: &ename, Expiration: cfg.Expiration}),
 					},
 				}
 
@@ -80,7 +80,7 @@ func (c StatusCommand) Items(arg, data string) (items []alfred.Item, err error)
 						Arg: &alfred.ItemArg{
 							Keyword: "status",
 							Mode:    alfred.ModeDo,
-							Data:    alfred.Stringify(statusConfig{NewState: cfg.NewState, StatusText: cfg.StatusText, StatusEmoji: &ename}),
+							Data:    alfred.Stringify(statusConfig{NewState: cfg.NewState, StatusText: cfg.StatusText, StatusEmoji: &ename, Expiration: cfg.Expiration}),
 						},
 					}
 
@@ -105,7 +105,7 @@ func (c StatusCommand) Items(arg, data string) (items []alfred.Item, err error)
 				Arg: &alfred.ItemArg{
 					Keyword: "status",
 					Mode:    alfred.ModeDo,
-					Data:    alfred.Stringify(statusConfig{NewState: cfg.NewState, StatusText: cfg.StatusText, StatusEmoji: &emoji}),
+					Data:    alfred.Stringify(statusConfig{NewState: cfg.NewState, StatusText: cfg.StatusText, StatusEmoji: &emoji, Expiration: cfg.Expiration}),
 				},
 			}
 
@@ -119,7 +119,7 @@ func (c StatusCommand) Items(arg, data string) (items []alfred.Item, err error)
 				Arg: &alfred.ItemArg{
 					Keyword: "status",
 					Mode:    alfred.ModeDo,
-					Data:    alfred.Stringify(statusConfig{NewState: cfg.NewState, StatusText: cfg.StatusText, StatusEmoji: &emoji}),
+					Data:    alfred.Stringify(statusConfig{NewState: cfg.NewState, StatusText: cfg.StatusText, StatusEmoji: &emoji, Expiration: cfg.Expiration}),
 				},
 			}
 
@@ -153,9 +153,13 @@ func (c StatusCommand) Items(arg, data string) (items []alfred.Item, err error)
 
 		var title string
 		var subtitle string
+		var remaining string
+		var expiration *int64
 
 		user := cache.Users[i]
 		presence := user.Presence
+		now := time.Now()
+		text := arg
 
 		if arg == "" {
 			title = user.Profile.StatusText
@@ -164,20 +168,47 @@ func (c StatusCommand) Items(arg, data string) (items []alfred.Item, err error)
 			} else {
 				subtitle = "Clear existing status message"
 			}
+
+			if user.Profile.StatusExpiration != 0 {
+				left := time.Unix(user.Profile.StatusExpiration, 0).Sub(now)
+				if left > 0 {
+					remaining = fmt.Sprintf(" (%s left)", formatRemaining(left))
+				} else {
+					remaining = " (expired)"
+				}
+			}
 		} else {
-			title = arg
 			subtitle = "Update status message"
+
+			// A trailing phrase like "for 45m" or "until 17:30" sets when the
+			// status expires
+			if message, expires, ok := parseStatusExpiration(arg, now); ok {
+				text = message
+				unix := expires.Unix()
+				expiration = &unix
+				subtitle += ", expires " + formatExpiration(expires, now)
+			}
+
+			title = text
 		}
 
 		item := alfred.Item{
 			Title:    title,
-			Subtitle: subtitle,
+			Subtitle: subtitle + remaining,
 			Arg: &alfred.ItemArg{
 				Keyword: "status",
-				Data:    alfred.Stringify(statusConfig{StatusText: &arg, StatusEmoji: &user.Profile.StatusEmoji}),
+				Data:    alfred.Stringify(statusConfig{StatusText: &text, StatusEmoji: &user.Profile.StatusEmoji, Expiration: expiration}),
 			},
 		}
 
+		// Show whether notifications are paused
+		if dnd, err := getDNDInfo(); err != nil {
+			dlog.Print("Unable to get DND info: ", err)
+		} else if dnd.IsActive(now) {
+			item.Title = fmt.Sprintf("%s %s", DNDMarker, item.Title)
+			item.Subtitle += fmt.Sprintf(" (notifications paused until %s)", dnd.Until(now).Format("15:04"))
+		}
+
 		if presence == PresenceAway {
 			item.Title = fmt.Sprintf("%s %s", AwayMarker, item.Title)
 		} else {
@@ -195,7 +226,7 @@ func (c StatusCommand) Items(arg, data string) (items []alfred.Item, err error)
 			Subtitle: modSubtitle,
 			Arg: &alfred.ItemArg{
 				Keyword: "status",
-				Data:    alfred.Stringify(statusConfig{NewState: PresenceActive, StatusText: &arg, StatusEmoji: &user.Profile.StatusEmoji}),
+				Data:    alfred.Stringify(statusConfig{NewState: PresenceActive, StatusText: &text, StatusEmoji: &user.Profile.StatusEmoji, Expiration: expiration}),
 			},
 		})
 
@@ -209,7 +240,7 @@ func (c StatusCommand) Items(arg, data string) (items []alfred.Item, err error)
 			Subtitle: modSubtitle,
 			Arg: &alfred.ItemArg{
 				Keyword: "status",
-				Data:    alfred.Stringify(statusConfig{NewState: PresenceAway, StatusText: &arg, StatusEmoji: &user.Profile.StatusEmoji}),
+				Data:    alfred.Stringify(statusConfig{NewState: PresenceAway, StatusText: &text, StatusEmoji: &user.Profile.StatusEmoji, Expiration: expiration}),
 			},
 		})
 
@@ -225,7 +256,26 @@ func (c StatusCommand) Items(arg, data string) (items []alfred.Item, err error)
 			}
 		}
 
-		items = append(items, item)
+		// Presets matching the query are listed before the status item so
+		// that they can be applied with a single action
+		items = append(presetItems(arg), item)
+
+		if arg == "" && len(config.Calendars) > 0 {
+			if calItem, found := calendarItem(now); found {
+				items = append(items, calItem)
+			}
+		}
+
+		history := loadStatusHistory()
+		if arg == "" {
+			if entry, found := previousStatus(history, user.Profile.StatusText, user.Profile.StatusEmoji); found {
+				items = append(items, historyItem(entry,
+					fmt.Sprintf("Previous status: %s", entry.Text),
+					fmt.Sprintf("Restore the status set %s ago", formatRemaining(now.Sub(entry.Time)))))
+			}
+		} else {
+			items = append(items, historyItems(history, text, now)...)
+		}
 	}
 
 	return
@@ -240,6 +290,27 @@ func (c StatusCommand) Do(data string) (out string, err error) {
 		}
 	}
 
+	if cfg.Preset != nil {
+		i := indexOfPreset(*cfg.Preset)
+		if i == -1 {
+			return "", fmt.Errorf(`Unknown preset "%s"`, *cfg.Preset)
+		}
+
+		preset := config.Presets[i]
+		cfg.NewState = preset.Presence
+		cfg.StatusText = &preset.Text
+		cfg.StatusEmoji = &preset.Emoji
+
+		var expiration time.Time
+		if expiration, err = preset.Expiration(); err != nil {
+			return
+		}
+		if !expiration.IsZero() {
+			expires := expiration.Unix()
+			cfg.Expiration = &expires
+		}
+	}
+
 	s := OpenSession(config.APIToken)
 	var errPresence error
 	var errStatus error
@@ -267,14 +338,34 @@ func (c StatusCommand) Do(data string) (out string, err error) {
 		statusText := *cfg.StatusText
 		statusEmoji := *cfg.StatusEmoji
 
-		if errStatus = s.SetStatus(statusText, statusEmoji); errStatus == nil {
+		var expiration time.Time
+		if cfg.Expiration != nil {
+			expiration = time.Unix(*cfg.Expiration, 0)
+		}
+
+		if errStatus = s.SetStatus(statusText, statusEmoji, expiration); errStatus == nil {
 			i := indexOfUserByID(cache.Auth.UserID)
 			if i == -1 {
 				dlog.Printf("The user cache is empty")
 				errStatus = fmt.Errorf("The user cache is empty")
 			} else {
+				// Keep the status being replaced in the history so that it
+				// can be restored
+				profile := cache.Users[i].Profile
+				if err := recordStatus(profile.StatusText, profile.StatusEmoji, time.Time{}); err != nil {
+					dlog.Println("Error saving status history:", err)
+				}
+				if err := recordStatus(statusText, statusEmoji, time.Now()); err != nil {
+					dlog.Println("Error saving status history:", err)
+				}
+
 				cache.Users[i].Profile.StatusText = statusText
 				cache.Users[i].Profile.StatusEmoji = statusEmoji
+				if cfg.Expiration != nil {
+					cache.Users[i].Profile.StatusExpiration = *cfg.Expiration
+				} else {
+					cache.Users[i].Profile.StatusExpiration = 0
+				}
 				if out != "" {
 					out += ", "
 				}
@@ -297,8 +388,41 @@ func (c StatusCommand) Do(data string) (out string, err error) {
 	return
 }
 
+// presetItems returns items for the status presets matching a query
+func presetItems(arg string) (items []alfred.Item) {
+	for _, preset := range config.Presets {
+		if alfred.FuzzyMatches(preset.Name, arg) {
+			name := preset.Name
+
+			item := alfred.Item{
+				Title:        name,
+				Subtitle:     preset.Description(),
+				Autocomplete: name,
+				Arg: &alfred.ItemArg{
+					Keyword: "status",
+					Mode:    alfred.ModeDo,
+					Data:    alfred.Stringify(statusConfig{Preset: &name}),
+				},
+			}
+
+			if preset.Emoji != "" {
+				if emojiFile, err := getEmojiIcon(preset.Emoji); err == nil {
+					item.Icon = emojiFile
+				}
+			}
+
+			items = append(items, item)
+		}
+	}
+
+	alfred.FuzzySort(items, arg)
+	return
+}
+
 type statusConfig struct {
 	NewState    Presence
 	StatusText  *string
 	StatusEmoji *string
+	Expiration  *int64
+	Preset      *string
 }
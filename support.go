@@ -2,15 +2,28 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
+	"os/exec"
 	"path"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/jason0x43/go-alfred"
 )
 
+// maxFilesCacheSize is the most space used by cached files and thumbnails
+const maxFilesCacheSize = 50 * 1024 * 1024
+
+// maxThumbnailDownloads is how many of the first files in a list may have
+// their thumbnails downloaded. Later files only use cached thumbnails.
+const maxThumbnailDownloads = 5
+
 func checkRefresh() error {
 	if time.Now().Sub(cache.Time).Minutes() < 5.0 {
 		return nil
@@ -121,12 +134,20 @@ type userPresence struct {
 	Presence Presence
 }
 
-func getChannel(id string) (c Channel, found bool) {
+func indexOfChannelByID(id string) (i int) {
 	for i := range cache.Channels {
 		if cache.Channels[i].ID == id {
-			return cache.Channels[i], true
+			return i
 		}
 	}
+	return -1
+}
+
+func getChannel(id string) (c Channel, found bool) {
+	i := indexOfChannelByID(id)
+	if i != -1 {
+		return cache.Channels[i], true
+	}
 	return
 }
 
@@ -147,19 +168,91 @@ func getUser(id string) (u User, found bool) {
 	return
 }
 
-func getFile(url string, filename string) (outFile string, err error) {
-	if filename == "" {
-		filename = path.Base(url)
+func getChannelByName(name string) (c Channel, found bool) {
+	for i := range cache.Channels {
+		if strings.EqualFold(cache.Channels[i].Name, name) {
+			return cache.Channels[i], true
+		}
+	}
+	return
+}
+
+func getUserByName(name string) (u User, found bool) {
+	for i := range cache.Users {
+		if strings.EqualFold(cache.Users[i].Name, name) {
+			return cache.Users[i], true
+		}
+	}
+	return
+}
+
+// fileVersionURL returns the URL of a version of a Slack file, and the
+// content type it must be downloaded as
+func fileVersionURL(file *File, size string) (url, expected string) {
+	switch size {
+	case "64":
+		return file.Thumb64, "image/"
+	case "360":
+		return file.Thumb360, "image/"
+	}
+	return file.PrivateURL, expectedContentType(file.MimeType)
+}
+
+// getCachedFile returns the path of a version of a Slack file. found is true
+// if the file has already been downloaded.
+func getCachedFile(file *File, size string) (outFile string, found bool) {
+	url, _ := fileVersionURL(file, size)
+
+	name := file.ID
+	if size != "" {
+		name += "_" + size
+	}
+	outFile = path.Join(filesDir, name+path.Ext(url))
+
+	if fileExists(outFile) {
+		now := time.Now()
+		os.Chtimes(outFile, now, now)
+		return outFile, true
+	}
+	return outFile, false
+}
+
+// getFile retrieves a version of a Slack file: "64" or "360" for its
+// thumbnails, or "" for the file itself. Files are cached by ID and size, and
+// the least recently used ones are removed when the cache grows too large.
+func getFile(file *File, size string) (outFile string, err error) {
+	url, expected := fileVersionURL(file, size)
+	if url == "" {
+		return "", fmt.Errorf("File %s has no %s version", file.ID, size)
+	}
+
+	var found bool
+	if outFile, found = getCachedFile(file, size); found {
+		return
 	}
-	outFile = path.Join(workflow.CacheDir(), filename)
-	if _, err = os.Stat(outFile); err == nil {
+
+	if err = downloadFile(url, outFile, expected); err != nil {
 		return
 	}
 
+	if err := evictFiles(filesDir, maxFilesCacheSize); err != nil {
+		dlog.Printf("Error evicting cached files: %v", err)
+	}
+
+	return
+}
+
+// downloadFile saves the content at a URL to a file. Requests to Slack are
+// authenticated with the API token. If expected is given, the response must
+// have a content type starting with it.
+func downloadFile(fileURL string, outFile string, expected string) (err error) {
 	var req *http.Request
-	if req, err = http.NewRequest("GET", url, nil); err != nil {
+	if req, err = http.NewRequest("GET", fileURL, nil); err != nil {
 		return
 	}
+	if isSlackURL(fileURL) {
+		req.Header.Set("Authorization", "Bearer "+config.APIToken)
+	}
 
 	var resp *http.Response
 	if resp, err = client.Do(req); err != nil {
@@ -167,15 +260,108 @@ func getFile(url string, filename string) (outFile string, err error) {
 	}
 	defer resp.Body.Close()
 
-	var content []byte
-	if content, err = ioutil.ReadAll(resp.Body); err != nil {
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf(resp.Status)
+	}
+
+	if err = validateContentType(resp.Header.Get("Content-Type"), expected); err != nil {
 		return
 	}
 
-	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
-		return "", fmt.Errorf(resp.Status)
+	// Write to a temporary file so a failed download doesn't leave a partial
+	// file behind
+	tmpFile := outFile + ".part"
+	var out *os.File
+	if out, err = os.OpenFile(tmpFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600); err != nil {
+		return
+	}
+	if _, err = io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		os.Remove(tmpFile)
+		return
+	}
+	if err = out.Close(); err != nil {
+		os.Remove(tmpFile)
+		return
+	}
+
+	return os.Rename(tmpFile, outFile)
+}
+
+// isSlackURL returns true if a URL is served by Slack, which means it can be
+// sent the API token
+func isSlackURL(fileURL string) bool {
+	u, err := url.Parse(fileURL)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == "slack.com" || strings.HasSuffix(host, ".slack.com") ||
+		host == "slack-files.com" || strings.HasSuffix(host, ".slack-files.com")
+}
+
+// expectedContentType returns the content type a download of a file with the
+// given mimetype must have. Only files that are HTML themselves may be
+// downloaded as HTML.
+func expectedContentType(mimeType string) string {
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil && mediaType == "text/html" {
+		return "text/html"
+	}
+	return ""
+}
+
+// validateContentType checks the content type of a downloaded file. Slack
+// answers unauthorized requests for private files with its login page, so an
+// HTML response is an error unless HTML was expected.
+func validateContentType(contentType, expected string) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(contentType)
+	}
+
+	if mediaType == "text/html" && !strings.HasPrefix(expected, "text/html") {
+		return fmt.Errorf("Received a web page instead of a file")
+	}
+	if expected != "" && !strings.HasPrefix(mediaType, expected) {
+		return fmt.Errorf("Expected %s content, got %s", expected, mediaType)
+	}
+
+	return nil
+}
+
+// evictFiles removes the least recently used files from a directory until
+// its total size is at most maxSize
+func evictFiles(dir string, maxSize int64) (err error) {
+	var infos []os.FileInfo
+	if infos, err = ioutil.ReadDir(dir); err != nil {
+		return
+	}
+
+	var total int64
+	for _, info := range infos {
+		total += info.Size()
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+
+	for _, info := range infos {
+		if total <= maxSize {
+			break
+		}
+		if err = os.Remove(path.Join(dir, info.Name())); err != nil {
+			return
+		}
+		total -= info.Size()
 	}
 
-	err = ioutil.WriteFile(outFile, content, 0600)
 	return
 }
+
+// copyToClipboard puts text on the system clipboard
+func copyToClipboard(text string) error {
+	cmd := exec.Command("pbcopy")
+	cmd.Stdin = strings.NewReader(text)
+	return cmd.Run()
+}
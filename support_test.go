@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// TestValidateContentType tests validateContentType
+func TestValidateContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		expected    string
+		ok          bool
+	}{
+		{"image/png", "image/", true},
+		{"image/jpeg; charset=binary", "image/", true},
+		{"text/html; charset=utf-8", "image/", false},
+		{"text/html", "", false},
+		{"text/html", "text/html", true},
+		{"application/pdf", "", true},
+		{"application/pdf", "image/", false},
+	}
+
+	for _, test := range tests {
+		err := validateContentType(test.contentType, test.expected)
+		if (err == nil) != test.ok {
+			t.Errorf("validateContentType(%q, %q): expected ok to be %v, got %v", test.contentType, test.expected, test.ok, err)
+		}
+	}
+}
+
+// TestExpectedContentType tests that only HTML files may be downloaded as
+// HTML
+func TestExpectedContentType(t *testing.T) {
+	for _, mimeType := range []string{"text/html", "text/html; charset=utf-8"} {
+		if err := validateContentType("text/html", expectedContentType(mimeType)); err != nil {
+			t.Errorf("Expected an HTML download of a %s file to be ok: %v", mimeType, err)
+		}
+	}
+
+	for _, mimeType := range []string{"", "application/pdf", "text/plain"} {
+		if err := validateContentType("text/html", expectedContentType(mimeType)); err == nil {
+			t.Errorf("Expected an HTML download of a %q file to fail", mimeType)
+		}
+	}
+}
+
+// TestIsSlackURL tests isSlackURL
+func TestIsSlackURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://files.slack.com/files-pri/T1-F1/logo.png": true,
+		"https://slack.com/api/files.list":                 true,
+		"https://slack-files.com/T1-F1-abc":                true,
+		"http://files.slack.com/files-pri/T1-F1/logo.png":  false,
+		"https://emoji.example.com/slack.com/party.png":    false,
+		"https://notslack.com/file.png":                    false,
+	}
+
+	for u, expected := range tests {
+		if isSlackURL(u) != expected {
+			t.Errorf("isSlackURL(%q): expected %v", u, expected)
+		}
+	}
+}
+
+// TestEvictFiles tests evictFiles
+func TestEvictFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	for i, name := range []string{"old", "middle", "new"} {
+		filename := path.Join(dir, name)
+		if err := ioutil.WriteFile(filename, make([]byte, 100), 0600); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(time.Duration(i) * time.Minute)
+		os.Chtimes(filename, modTime, modTime)
+	}
+
+	if err := evictFiles(dir, 250); err != nil {
+		t.Fatal(err)
+	}
+
+	if fileExists(path.Join(dir, "old")) {
+		t.Error("Expected the oldest file to be removed")
+	}
+	if !fileExists(path.Join(dir, "middle")) || !fileExists(path.Join(dir, "new")) {
+		t.Error("Expected newer files to be kept")
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+// maxUsesPerItem is the number of recent uses kept for each channel or user
+const maxUsesPerItem = 10
+
+// maxUsageAge is how long a use is remembered
+const maxUsageAge = 90 * 24 * time.Hour
+
+// Ranking weights. An item's fuzzy match position is worth up to
+// fuzzyWeight, and each recent use adds up to 100 points of frecency.
+const (
+	fuzzyWeight = 50.0
+	groupWeight = 25.0
+)
+
+// usageLog records when channels and users were opened, keyed by ID
+type usageLog map[string][]int64
+
+func loadUsage() (usage usageLog) {
+	usage = usageLog{}
+	if !fileExists(usageFile) {
+		return
+	}
+	if err := alfred.LoadJSON(usageFile, &usage); err != nil {
+		dlog.Println("Error loading usage log:", err)
+	}
+	return
+}
+
+// recordUsage notes that channels or users were used now
+func recordUsage(ids ...string) (err error) {
+	usage := loadUsage()
+	now := time.Now()
+	for _, id := range ids {
+		usage.Add(id, now)
+	}
+	usage.Prune(now)
+	return alfred.SaveJSON(usageFile, &usage)
+}
+
+// Add records a use of an item
+func (u usageLog) Add(id string, t time.Time) {
+	uses := append(u[id], t.Unix())
+	if len(uses) > maxUsesPerItem {
+		uses = uses[len(uses)-maxUsesPerItem:]
+	}
+	u[id] = uses
+}
+
+// Prune forgets uses that are too old to matter
+func (u usageLog) Prune(now time.Time) {
+	cutoff := now.Add(-maxUsageAge).Unix()
+	for id, uses := range u {
+		var recent []int64
+		for _, use := range uses {
+			if use >= cutoff {
+				recent = append(recent, use)
+			}
+		}
+		if len(recent) == 0 {
+			delete(u, id)
+		} else {
+			u[id] = recent
+		}
+	}
+}
+
+// Frecency scores an item by how often and how recently it was used. Recent
+// uses count for more than old ones.
+func (u usageLog) Frecency(id string, now time.Time) (score float64) {
+	for _, use := range u[id] {
+		age := now.Sub(time.Unix(use, 0))
+		switch {
+		case age < 4*time.Hour:
+			score += 100
+		case age < 24*time.Hour:
+			score += 80
+		case age < 3*24*time.Hour:
+			score += 60
+		case age < 7*24*time.Hour:
+			score += 40
+		case age < 30*24*time.Hour:
+			score += 20
+		default:
+			score += 10
+		}
+	}
+	return
+}
+
+// rankItems orders fuzzy sorted items by a blend of their match position and
+// a score for each item, like its frecency. Items with equal ranks keep
+// their order.
+func rankItems(items []alfred.Item, score func(item alfred.Item) float64) {
+	n := float64(len(items))
+	ranks := map[string]float64{}
+	for i, item := range items {
+		ranks[item.Autocomplete] = fuzzyWeight*(n-float64(i))/n + score(item)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return ranks[items[i].Autocomplete] > ranks[items[j].Autocomplete]
+	})
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jason0x43/go-alfred"
+)
+
+// TestUsageLog tests adding, pruning and scoring uses
+func TestUsageLog(t *testing.T) {
+	now := time.Date(2017, time.May, 10, 14, 20, 0, 0, time.Local)
+	usage := usageLog{}
+
+	usage.Add("recent", now.Add(-time.Hour))
+	usage.Add("recent", now.Add(-2*time.Hour))
+	usage.Add("old", now.AddDate(0, 0, -20))
+	usage.Add("ancient", now.AddDate(-1, 0, 0))
+
+	for i := 0; i < maxUsesPerItem+5; i++ {
+		usage.Add("busy", now)
+	}
+	if len(usage["busy"]) != maxUsesPerItem {
+		t.Errorf("Expected %d uses, got %d", maxUsesPerItem, len(usage["busy"]))
+	}
+
+	usage.Prune(now)
+	if _, found := usage["ancient"]; found {
+		t.Error("Expected ancient uses to be pruned")
+	}
+
+	if recent, old := usage.Frecency("recent", now), usage.Frecency("old", now); recent <= old {
+		t.Errorf("Expected recent (%v) to score higher than old (%v)", recent, old)
+	}
+	if score := usage.Frecency("unknown", now); score != 0 {
+		t.Errorf("Expected unused items to score 0, got %v", score)
+	}
+}
+
+// TestRankItems tests rankItems
+func TestRankItems(t *testing.T) {
+	items := []alfred.Item{
+		{Autocomplete: "design"},
+		{Autocomplete: "deploys"},
+		{Autocomplete: "dev"},
+	}
+	scores := map[string]float64{"deploys": 100}
+
+	rankItems(items, func(item alfred.Item) float64 {
+		return scores[item.Autocomplete]
+	})
+
+	expected := []string{"deploys", "design", "dev"}
+	for i, name := range expected {
+		if items[i].Autocomplete != name {
+			t.Errorf("Expected %s at position %d, got %s", name, i, items[i].Autocomplete)
+		}
+	}
+}
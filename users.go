@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -104,7 +105,24 @@ func (c UsersCommand) Items(arg, data string) (items []alfred.Item, err error) {
 				Title: fmt.Sprintf("Email: %s", user.Profile.Email),
 			})
 		}
+	} else if channel != nil && cfg.ToConfirmKick != nil {
+		items = kickConfirmationItems(channel, *cfg.ToConfirmKick)
 	} else {
+		var onlyFavorites bool
+		arg, onlyFavorites = parseFavoritesFilter(arg)
+
+		// A query like "bob running late" sends a message to bob. Once users
+		// have been selected for a group message, the whole query is the
+		// message.
+		if !cfg.Inviting {
+			if item, found := directMessageItem(cfg.Selected, arg); found {
+				items = append(items, item)
+			}
+		}
+
+		// Listed users by name, for ranking
+		users := map[string]User{}
+
 		for _, user := range cache.Users {
 			if user.Deleted {
 				dlog.Print("Skipping deleted user ", user.Name)
@@ -116,10 +134,55 @@ func (c UsersCommand) Items(arg, data string) (items []alfred.Item, err error) {
 				continue
 			}
 
+			users[user.Name] = user
+
+			if onlyFavorites && !isFavorite(user.ID) {
+				continue
+			}
+
+			// When inviting, only people who aren't already members are listed
+			if channel != nil && cfg.Inviting {
+				if !isInChannel(user.ID, channel) && (alfred.FuzzyMatches(user.Name, arg) || alfred.FuzzyMatches(user.Profile.RealName, arg)) {
+					items = append(items, alfred.Item{
+						Title:        user.Name,
+						Subtitle:     fmt.Sprintf("Invite to #%s", channel.Name),
+						Autocomplete: user.Name,
+						Arg: &alfred.ItemArg{
+							Keyword: "users",
+							Mode:    alfred.ModeDo,
+							Data: alfred.Stringify(&userConfig{
+								ToInvite: &channelMember{Channel: channel.ID, User: user.ID},
+							}),
+						},
+					})
+				}
+				continue
+			}
+
 			if channel != nil && !isInChannel(user.ID, channel) {
 				continue
 			}
 
+			if len(cfg.Selected) > 0 {
+				if isSelected(user.ID, cfg.Selected) {
+					continue
+				}
+
+				if alfred.FuzzyMatches(user.Name, arg) || alfred.FuzzyMatches(user.Profile.RealName, arg) {
+					items = append(items, alfred.Item{
+						Title:    user.Name,
+						Subtitle: "Add to the group message",
+						Arg: &alfred.ItemArg{
+							Keyword: "users",
+							Data: alfred.Stringify(&userConfig{
+								Selected: append(append([]string{}, cfg.Selected...), user.ID),
+							}),
+						},
+					})
+				}
+				continue
+			}
+
 			if alfred.FuzzyMatches(user.ID, arg) || alfred.FuzzyMatches(user.Profile.RealName, arg) {
 				item := alfred.Item{
 					Title:        user.Name,
@@ -131,6 +194,10 @@ func (c UsersCommand) Items(arg, data string) (items []alfred.Item, err error) {
 					},
 				}
 
+				if isFavorite(user.ID) {
+					item.Title = fmt.Sprintf("%s %s", FavoriteMarker, item.Title)
+				}
+
 				if user.Presence == PresenceAway {
 					item.Title = fmt.Sprintf("%s %s", AwayMarker, item.Title)
 				} else {
@@ -165,6 +232,14 @@ func (c UsersCommand) Items(arg, data string) (items []alfred.Item, err error) {
 					},
 				})
 
+				item.AddMod(alfred.ModShift, alfred.ItemMod{
+					Subtitle: "Start a group message",
+					Arg: &alfred.ItemArg{
+						Keyword: "users",
+						Data:    alfred.Stringify(&userConfig{Selected: []string{user.ID}}),
+					},
+				})
+
 				item.AddMod(alfred.ModAlt, alfred.ItemMod{
 					Subtitle: "Open profile",
 					Arg: &alfred.ItemArg{
@@ -179,12 +254,59 @@ func (c UsersCommand) Items(arg, data string) (items []alfred.Item, err error) {
 					},
 				})
 
+				addFavoriteMod(&item, user.ID)
+
+				if channel != nil && user.ID != cache.Auth.UserID {
+					item.AddMod(alfred.ModCtrl, alfred.ItemMod{
+						Subtitle: fmt.Sprintf("Remove from #%s", channel.Name),
+						Arg: &alfred.ItemArg{
+							Keyword: "users",
+							Data: alfred.Stringify(&userConfig{
+								Channel:       &channel.ID,
+								ToConfirmKick: &user.ID,
+							}),
+						},
+					})
+				}
+
 				items = append(items, item)
 			}
 		}
 
-		alfred.FuzzySort(items, arg)
-		sort.Stable(byStatus(items))
+		if len(cfg.Selected) == 0 {
+			alfred.FuzzySort(items, arg)
+
+			// Frequently contacted users come first, then active ones
+			usage := loadUsage()
+			now := time.Now()
+			rankItems(items, func(item alfred.Item) float64 {
+				user := users[item.Autocomplete]
+				score := usage.Frecency(user.ID, now)
+				if user.Presence != PresenceAway {
+					score += groupWeight
+				}
+				return score
+			})
+
+			favorites := favoriteSet()
+			sort.SliceStable(items, func(i, j int) bool {
+				return favorites[users[items[i].Autocomplete].ID] && !favorites[users[items[j].Autocomplete].ID]
+			})
+
+			if channel != nil && !cfg.Inviting && alfred.FuzzyMatches("invite", arg) {
+				items = append([]alfred.Item{{
+					Title:    "Invite…",
+					Subtitle: fmt.Sprintf("Add people to #%s", channel.Name),
+					Arg: &alfred.ItemArg{
+						Keyword: "users",
+						Data: alfred.Stringify(&userConfig{
+							Channel:  &channel.ID,
+							Inviting: true,
+						}),
+					},
+				}}, items...)
+			}
+		}
 	}
 
 	return
@@ -200,22 +322,115 @@ func (c UsersCommand) Do(data string) (out string, err error) {
 	}
 
 	if cfg.ToMessage != nil {
+		users := cfg.ToMessage.Users
+		if len(users) == 0 {
+			users = []string{cfg.ToMessage.User}
+		}
+		if err := recordUsage(users...); err != nil {
+			dlog.Printf("Error recording usage: %v", err)
+		}
+
 		var channel string
 		s := OpenSession(config.APIToken)
-		if channel, err = s.OpenDirectMessage(cfg.ToMessage.User); err != nil {
+		if len(cfg.ToMessage.Users) > 0 {
+			channel, err = s.OpenConversation(cfg.ToMessage.Users)
+		} else {
+			channel, err = s.OpenDirectMessage(cfg.ToMessage.User)
+		}
+		if err != nil {
 			return
 		}
 		time.Sleep(1 * time.Second)
 		browser.OpenURL(fmt.Sprintf("slack://channel?team=%s&id=%s", cfg.ToMessage.Team, channel))
 	}
 
+	if cfg.ToInvite != nil {
+		s := OpenSession(config.APIToken)
+		if err = s.InviteToChannel(cfg.ToInvite.Channel, []string{cfg.ToInvite.User}); err != nil {
+			return
+		}
+		out, err = updateMember(*cfg.ToInvite, true)
+	}
+
+	if cfg.ToKick != nil {
+		s := OpenSession(config.APIToken)
+		if err = s.KickFromChannel(cfg.ToKick.Channel, cfg.ToKick.User); err != nil {
+			if e, ok := err.(*apiError); ok {
+				if message, found := kickErrors[e.Code]; found {
+					err = errors.New(message)
+				}
+			}
+			return
+		}
+		out, err = updateMember(*cfg.ToKick, false)
+	}
+
 	if cfg.ToOpen != nil {
+		if err := recordUsage(cfg.ToOpen.User); err != nil {
+			dlog.Printf("Error recording usage: %v", err)
+		}
 		browser.OpenURL(fmt.Sprintf("slack://user?team=%s&id=%s", cfg.ToOpen.Team, cfg.ToOpen.User))
 	}
 
 	return
 }
 
+// kickErrors explain the reasons Slack gives for refusing to remove a user
+// from a channel
+var kickErrors = map[string]string{
+	"cant_kick_from_general": "Nobody can be removed from the general channel",
+	"cant_kick_self":         "You can't remove yourself; leave the channel instead",
+	"not_in_channel":         "That user isn't a member of the channel",
+	"restricted_action":      "Your workspace doesn't allow you to remove people from this channel",
+	"missing_scope":          "The API token isn't allowed to remove people from channels",
+}
+
+// kickConfirmationItems asks for confirmation before removing a user from a
+// channel
+func kickConfirmationItems(channel *Channel, uid string) (items []alfred.Item) {
+	user, _ := getUser(uid)
+
+	items = append(items, alfred.Item{
+		Title:    fmt.Sprintf("Remove @%s from #%s", user.Name, channel.Name),
+		Subtitle: "Action to confirm",
+		Arg: &alfred.ItemArg{
+			Keyword: "users",
+			Mode:    alfred.ModeDo,
+			Data: alfred.Stringify(&userConfig{
+				ToKick: &channelMember{Channel: channel.ID, User: uid},
+			}),
+		},
+	})
+
+	items = append(items, alfred.Item{
+		Title:    "Cancel",
+		Subtitle: "Go back to the member list",
+		Arg: &alfred.ItemArg{
+			Keyword: "users",
+			Data:    alfred.Stringify(&userConfig{Channel: &channel.ID}),
+		},
+	})
+
+	return
+}
+
+// updateMember updates the cached member list of a channel after a user is
+// invited or removed
+func updateMember(m channelMember, invited bool) (out string, err error) {
+	var channel Channel
+	if channel, err = setMember(m.Channel, m.User, invited); err != nil {
+		return
+	}
+
+	user, _ := getUser(m.User)
+	if invited {
+		out = fmt.Sprintf("Invited @%s to #%s", user.Name, channel.Name)
+	} else {
+		out = fmt.Sprintf("Removed @%s from #%s", user.Name, channel.Name)
+	}
+	return
+}
+
 func isInChannel(userID string, channel *Channel) bool {
 	for _, uid := range channel.Members {
 		if uid == userID {
@@ -225,29 +440,91 @@ func isInChannel(userID string, channel *Channel) bool {
 	return false
 }
 
-type dmID struct {
-	User string
-	Team string
-}
+// directMessageItem returns an item that sends the text of a query to one or
+// more users
+func directMessageItem(selected []string, arg string) (item alfred.Item, found bool) {
+	var users []User
+	text := arg
 
-type userConfig struct {
-	User      string
-	ToMessage *dmID
-	ToOpen    *dmID
-	Channel   *string
+	if len(selected) > 0 {
+		for _, id := range selected {
+			if user, ok := getUser(id); ok {
+				users = append(users, user)
+			}
+		}
+	} else {
+		parts := strings.SplitN(strings.TrimSpace(arg), " ", 2)
+		if len(parts) < 2 {
+			return
+		}
+		user, ok := getUserByName(strings.TrimPrefix(parts[0], "@"))
+		if !ok {
+			return
+		}
+		users = []User{user}
+		text = parts[1]
+	}
+
+	if len(users) == 0 {
+		return
+	}
+
+	var ids, names []string
+	for _, user := range users {
+		ids = append(ids, user.ID)
+		names = append(names, "@"+user.Name)
+	}
+	name := strings.Join(names, ", ")
+
+	if strings.TrimSpace(text) == "" {
+		item = alfred.Item{
+			Title:    fmt.Sprintf("Message %s", name),
+			Subtitle: "Type a message, or action to open the conversation in Slack",
+			Arg: &alfred.ItemArg{
+				Keyword: "users",
+				Mode:    alfred.ModeDo,
+				Data: alfred.Stringify(&userConfig{
+					ToMessage: &dmID{
+						Users: ids,
+						Team:  cache.Auth.TeamID,
+					},
+				}),
+			},
+		}
+		return item, true
+	}
+
+	return sendItem(name, outgoingMessage{Users: ids, Text: text}), true
 }
 
-type byStatus alfred.Items
+func isSelected(userID string, selected []string) bool {
+	for _, id := range selected {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
 
-func (b byStatus) Len() int {
-	return len(b)
+type dmID struct {
+	User  string
+	Users []string
+	Team  string
 }
 
-func (b byStatus) Swap(i, j int) {
-	b[i], b[j] = b[j], b[i]
+type userConfig struct {
+	User          string
+	ToMessage     *dmID
+	ToOpen        *dmID
+	Channel       *string
+	Selected      []string
+	Inviting      bool
+	ToConfirmKick *string
+	ToInvite      *channelMember
+	ToKick        *channelMember
 }
 
-func (b byStatus) Less(i, j int) bool {
-	// TODO Actually look at the user, not just item data
-	return strings.HasPrefix(b[i].Title, string(ActiveMarker)) && strings.HasPrefix(b[j].Title, string(AwayMarker))
+type channelMember struct {
+	Channel string
+	User    string
 }